@@ -72,9 +72,12 @@ func case2() *Case {
 		InjuriesOrDamages: []InjuryOrDamage{petersCar1},
 	}
 	speeding := &BrokenLegalRequirement{
-		Description:  "Peter was speeding and overtaking David",
-		Persons:      []*Person{peter},
-		Consequences: []Event{davidsCarHitsPetersCar},
+		Description:      "Peter was speeding and overtaking David",
+		Persons:          []*Person{peter},
+		Consequences:     []Event{davidsCarHitsPetersCar},
+		ProtectedClass:   "other road users and the people along the road",
+		ProtectedPersons: []*Person{david, kevin},
+		HarmTypes:        []HarmType{BodilyHarm, PropertyHarm},
 	}
 	peterIsOvertakingAndSpeeding := &Act{
 		Description:  "Peter is speeding and overtaking David in the lefthand lane",
@@ -123,9 +126,57 @@ func case2() *Case {
 	}
 }
 
+// The doctrinal res ipsa loquitur case: Byrne v Boadle (1863).
+func case3() *Case {
+	byrne := &Person{Name: "Byrne"}
+	boadle := &Person{Name: "Boadle"}
+
+	keepBarrelsSafe := &Duty{
+		Description: "A dealer in flour should make sure that its barrels do not fall onto the street",
+		OwedFrom:    []*Person{boadle},
+		OwedTo:      []*Person{byrne},
+	}
+
+	byrnesInjury := &BodilyInjury{
+		Description: "Byrne is knocked down and seriously injured",
+		Persons:     []*Person{byrne},
+	}
+	barrelHitsByrne := &PassiveEvent{
+		Description:       "The barrel hits Byrne",
+		InjuriesOrDamages: []InjuryOrDamage{byrnesInjury},
+	}
+	barrelFalls := &PassiveEvent{
+		Description:  "A barrel of flour falls from the window of Boadle's warehouse",
+		Consequences: []Event{barrelHitsByrne},
+		Duty:         keepBarrelsSafe,
+		ResIpsa: &ResIpsa{
+			UnknownMechanism:    true,
+			OrdinarilyNegligent: true,
+			ExclusiveControl:    boadle,
+		},
+	}
+	byrneWalksByTheWarehouse := &Act{
+		Description:  "Byrne walks along the street past Boadle's warehouse",
+		Person:       byrne,
+		Consequences: []Event{barrelHitsByrne},
+	}
+
+	return &Case{
+		ShortName:    "case_flour_barrel",
+		RootEvents:   []Event{barrelFalls, byrneWalksByTheWarehouse},
+		SubQuestions: []string{"resIpsa", "defendants", "duty", "primaFacie"},
+		Text: []string{
+			"Byrne was walking along the street past the warehouse of Boadle, a dealer in flour. A barrel of " +
+				"flour fell from a window above the street, hit Byrne and knocked him down, seriously injuring " +
+				"him. Nobody saw how the barrel came to fall. The barrels were being lowered from the warehouse " +
+				"by Boadle's men, and nobody else had access to them.",
+		},
+	}
+}
+
 // GetContent returns the content that NITS operates on.
 // This is test content containing a few multiple choice / proposition
-// questions and two cases.
+// questions and three cases.
 func GetContent() *Content {
 	return &Content{
 		Questions: []Question{
@@ -344,6 +395,7 @@ func GetContent() *Content {
 			},
 			DefaultCase(),
 			case2(),
+			case3(),
 		},
 	}
 }
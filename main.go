@@ -1,8 +1,35 @@
 package main
 
+import (
+	"flag"
+	"fmt"
+	"os"
+)
 import "./nits"
 import "./content"
 
 func main() {
-	nits.Run(content.GetContent())
+	flag.Parse()
+	var err error
+	// The linter loads the content itself, so that it can report all the
+	// problems in it instead of stopping at the first one.
+	if flag.Arg(0) == "lint" {
+		err = nits.Lint(content.GetContent, flag.Args()[1:])
+	} else {
+		var c *nits.Content
+		if c, err = nits.SelectContent(content.GetContent); err == nil {
+			switch flag.Arg(0) {
+			case "fit":
+				err = nits.Fit(c, flag.Args()[1:])
+			case "export":
+				err = nits.Export(c, flag.Args()[1:])
+			default:
+				nits.Run(c)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
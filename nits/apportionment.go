@@ -0,0 +1,179 @@
+package nits
+
+// This file implements the apportionment sub question. It asks the student
+// how much a plaintiff who is partly at fault can recover under the
+// negligence rule of the jurisdiction of the case.
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// The apportionment steps, one per negligence rule, so that answers train
+// the concepts of the rule that was applied. Only the step for the rule of
+// the jurisdiction of a case is part of that case.
+var apportionmentSteps = map[NegligenceRule]subQuestion{
+	PureComparative:       addStep(&step{parent: "apportionment", tag: "apportionment.pure", concepts: []*Concept{ComparativeNegligence1, PureComparativeNegligence1}, inCase: underRule(PureComparative)}),
+	ModifiedComparative50: addStep(&step{parent: "apportionment", tag: "apportionment.modified50", concepts: []*Concept{ComparativeNegligence1, ModifiedComparativeNegligence1}, inCase: underRule(ModifiedComparative50)}),
+	ModifiedComparative51: addStep(&step{parent: "apportionment", tag: "apportionment.modified51", concepts: []*Concept{ComparativeNegligence1, ModifiedComparativeNegligence1}, inCase: underRule(ModifiedComparative51)}),
+	Contributory:          addStep(&step{parent: "apportionment", tag: "apportionment.contributory", concepts: []*Concept{ContributoryNegligence1}, inCase: underRule(Contributory)}),
+}
+
+// underRule returns a function that tells if a case is tried under a
+// negligence rule.
+func underRule(rule NegligenceRule) func(*preprocessedCase) bool {
+	return func(pp *preprocessedCase) bool {
+		return pp.jurisdiction != nil && pp.jurisdiction.Rule == rule
+	}
+}
+
+// String returns a description of a negligence rule.
+func (r NegligenceRule) String() string {
+	switch r {
+	case PureComparative:
+		return "pure comparative negligence"
+	case ModifiedComparative50:
+		return "modified comparative negligence (50% bar)"
+	case ModifiedComparative51:
+		return "modified comparative negligence (51% bar)"
+	case Contributory:
+		return "contributory negligence"
+	}
+	return "no negligence rule"
+}
+
+// recoverable computes the amount that a plaintiff with a share of the
+// fault can recover under a negligence rule. It also returns the steps of
+// the computation, for explaining it to the student.
+func recoverable(rule NegligenceRule, amount, share int) (int, []string) {
+	steps := []string{
+		fmt.Sprintf("The compensatory damages are $%d and the plaintiff is %d%% at fault.", amount, share),
+	}
+	barred := false
+	switch rule {
+	case Contributory:
+		barred = share > 0
+		steps = append(steps, "Under contributory negligence any fault of the plaintiff bars recovery.")
+	case ModifiedComparative50:
+		barred = share >= 50
+		steps = append(steps, "Under the 50% bar rule a plaintiff who is 50% or more at fault can not recover.")
+	case ModifiedComparative51:
+		barred = share >= 51
+		steps = append(steps, "Under the 51% bar rule a plaintiff who is 51% or more at fault can not recover.")
+	case PureComparative:
+		steps = append(steps, "Under pure comparative negligence the plaintiff is never barred from recovery.")
+	}
+	if barred {
+		steps = append(steps, fmt.Sprintf("At %d%% the plaintiff is barred, so the recoverable amount is $0.", share))
+		return 0, steps
+	}
+	if rule == Contributory {
+		steps = append(steps, fmt.Sprintf("The plaintiff is not at fault and recovers the full $%d.", amount))
+		return amount, steps
+	}
+	result := amount * (100 - share) / 100
+	steps = append(steps, fmt.Sprintf("The damages are reduced by the plaintiff's share: $%d x %d%% = $%d.", amount, 100-share, result))
+	return result, steps
+}
+
+// faultShare returns the percentage of the fault for a damage that is
+// attributed to a person.
+func faultShare(dam InjuryOrDamage, person *Person) int {
+	for _, f := range dam.getFault() {
+		if f.Person == person {
+			return f.Percentage
+		}
+	}
+	return 0
+}
+
+// apportionable returns the damages in a case for which the recoverable
+// amount can be computed: damages with compensatory amounts, fault shares
+// and persons who suffered them.
+func (p *preprocessedCase) apportionable() []InjuryOrDamage {
+	result := make([]InjuryOrDamage, 0)
+	for dam := range p.injuriesOrDamages {
+		if compensatory(p.jurisdiction, dam) > 0 && len(dam.getFault()) > 0 && len(dam.GetPersons()) > 0 {
+			result = append(result, dam)
+		}
+	}
+	p.sortDamages(result)
+	return result
+}
+
+type apportionmentSubQuestion struct{}
+
+func (a *apportionmentSubQuestion) getTag() string {
+	return "apportionment"
+}
+
+// getConcepts returns no concepts: answers are registered against the step
+// for the rule of the case, which has the concepts.
+func (a *apportionmentSubQuestion) getConcepts() []*Concept {
+	return nil
+}
+
+func (a *apportionmentSubQuestion) applies(pp *preprocessedCase) bool {
+	return pp.jurisdiction != nil && apportionmentSteps[pp.jurisdiction.Rule] != nil && len(pp.apportionable()) > 0
+}
+
+var _ = addSubQuestion(&apportionmentSubQuestion{})
+
+// newInstance picks a random damage and a person who suffered it.
+func (a *apportionmentSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	dams := pp.apportionable()
+	if len(dams) == 0 {
+		return nil
+	}
+	dam := dams[r.Intn(len(dams))]
+	return pp.newInstance(a, dam, dam.GetPersons()[r.Intn(len(dam.GetPersons()))])
+}
+
+// ask asks the apportionment sub question.
+func (a *apportionmentSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	dam, ok1 := pp.ref(inst, 0).(InjuryOrDamage)
+	plaintiff, ok2 := pp.ref(inst, 1).(*Person)
+	if !ok1 || !ok2 || c.Jurisdiction == nil || apportionmentSteps[c.Jurisdiction.Rule] == nil {
+		return badInstance(ui, inst)
+	}
+	rightAnswer, steps := recoverable(c.Jurisdiction.Rule, compensatory(c.Jurisdiction, dam), faultShare(dam, plaintiff))
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("This case is tried in %s, which follows %s.", c.Jurisdiction.Name, c.Jurisdiction.Rule)
+		ui.println("Consider this injury or property damage:")
+		ui.println(dam.GetDescription())
+		ui.println("The compensatory damages are $%d and the fault is divided as follows:", compensatory(c.Jurisdiction, dam))
+		for _, f := range dam.getFault() {
+			ui.println("- %s: %d%%", f.Person.Name, f.Percentage)
+		}
+		ui.println("How much can %s recover?", plaintiff.Name)
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses := make([]string, 0)
+
+	for {
+		answer, ret := ui.getAmount()
+		if ret {
+			return ret
+		}
+		responses = append(responses, fmt.Sprintf("%d", answer))
+		if answer == rightAnswer {
+			ui.println("Correct :-)")
+			break
+		}
+		ui.println("Please try again :-(")
+	}
+	for _, s := range steps {
+		ui.println("%s", s)
+	}
+	state.registerAnswer(c, apportionmentSteps[c.Jurisdiction.Rule], responses)
+	return false
+}
@@ -0,0 +1,52 @@
+package nits
+
+import "testing"
+
+func TestRecoverable(t *testing.T) {
+	for _, test := range []struct {
+		rule  NegligenceRule
+		share int
+		want  int
+	}{
+		{PureComparative, 0, 1000},
+		{PureComparative, 90, 100},
+		{ModifiedComparative50, 49, 510},
+		{ModifiedComparative50, 50, 0},
+		{ModifiedComparative51, 50, 500},
+		{ModifiedComparative51, 51, 0},
+		{Contributory, 0, 1000},
+		{Contributory, 1, 0},
+	} {
+		if got, _ := recoverable(test.rule, 1000, test.share); got != test.want {
+			t.Errorf("recoverable(%v, 1000, %d); got: %d, want: %d", test.rule, test.share, got, test.want)
+		}
+	}
+
+	c := DefaultCase()
+	pp := c.preprocess()
+	if got := len(pp.apportionable()); got != 3 {
+		t.Errorf("apportionable(); got: %d damages, want: 3", got)
+	}
+	for _, dam := range pp.apportionable() {
+		if dam.GetPersons()[0].Name != "Bruce" {
+			continue
+		}
+		if got, _ := recoverable(c.Jurisdiction.Rule, compensatory(c.Jurisdiction, dam), faultShare(dam, dam.GetPersons()[0])); got != 0 {
+			t.Errorf("recoverable(Bruce's damage); got: %d, want: 0", got)
+		}
+	}
+}
+
+func TestApportionableWithoutPersons(t *testing.T) {
+	ann := &Person{Name: "Ann"}
+	dam := &PropertyDamage{
+		Description: "A fence is damaged",
+		Amounts:     []*DamageAmount{{Category: Economic, Description: "Repairs", Dollars: 1000}},
+		Fault:       []*FaultShare{{Person: ann, Percentage: 100}},
+	}
+	act := &Act{Description: "Ann drives into a fence", Person: ann, InjuriesOrDamages: []InjuryOrDamage{dam}}
+	c := &Case{ShortName: "case_fence", RootEvents: []Event{act}, Jurisdiction: &Jurisdiction{Name: "Nowhere", Rule: PureComparative}}
+	if sqMap["apportionment"].applies(c.preprocess()) {
+		t.Errorf("applies() for a damage without persons; got: true, want: false")
+	}
+}
@@ -3,26 +3,14 @@ package nits
 // This file contains all the logic related to Bayesian Knowledge Tracing.
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
-	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"os/exec"
-	"path"
-	"path/filepath"
-	"runtime"
+	"math/rand"
 	"sort"
-	"strconv"
-	"strings"
+	"time"
 )
 
 const (
-	correct   = "1"  // Indicator for trainhmm.
-	incorrect = "2"  // Indicator for trainhmm.
-	separator = "~"  // Separator for the concepts in the input file for trainhmm.
 	threshold = 0.95 // When do we consider a concept mastered?
 	pInit     = 0.1  // Probability that concept was known a-priory.
 	pLearn    = 0.2  // Probability that concept will transfer to mastered after a practice attempt.
@@ -30,49 +18,68 @@ const (
 	pGuess    = 0.5  // Probability that an unmastered skill is applied incorrectly.
 )
 
-var trainhmmPath string // Path to the trainhmm binary
+var (
+	bktFlag    = flag.String("bkt", "native", "BKT implementation to use: native or trainhmm")
+	bktFitFlag = flag.Bool("bkt_fit", true, "Fit the BKT parameters on the student's answers before tracing (native only; declared parameters are only refitted if this is set explicitly)")
+)
 
-// --------------------------------------------------------------------
+// bktParams are the parameters of the hidden Markov model that underlies
+// Bayesian Knowledge Tracing.
+type bktParams struct {
+	pInit  float64
+	pLearn float64
+	pSlip  float64
+	pGuess float64
+}
 
-// try tries a possible hmmPath and returns an error if this is certainly not
-// the trainhmm binary we want.
-func try(hmmPath string) error {
-	fi, err := os.Stat(hmmPath)
-	if err != nil {
-		return err
-	}
-	if fi.Mode()&os.ModeType != 0 {
-		return errors.New(fmt.Sprintf("%s: illegal file type", hmmPath))
-	}
-	if fi.Mode()&0555 != 0555 {
-		return errors.New(fmt.Sprintf("%s: illegal access mode (executable?)", hmmPath))
-	}
-	println("HMM training binary is in", hmmPath)
-	trainhmmPath = hmmPath
-	return nil
+// defaultBKTParams are the parameters used when nothing better is known.
+var defaultBKTParams = bktParams{pInit: pInit, pLearn: pLearn, pSlip: pSlip, pGuess: pGuess}
+
+// bktBackend is an implementation of Bayesian Knowledge Tracing. It takes
+// the registered answers of a student and computes the skill scores of all
+// the concepts involved in these answers.
+type bktBackend interface {
+	getName() string
+	init() error
+	train(answers []*answer) (map[*Concept]float64, error)
 }
 
-// initBKT initialized the Bayesian Knowledge Training module.
-// Its most important job is to find the trainhmm binary.
+// backend is the BKT backend that is in use.
+var backend bktBackend
+
+// initBKT initializes the Bayesian Knowledge Tracing module by selecting
+// the backend that was asked for on the command line.
 func initBKT() {
-	var err error
-	errors := make([]error, 0)
-	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
-	if err != nil {
-		panic("Can't determine the directory where the executable lives")
-	}
-	if err = try(path.Join(dir, fmt.Sprintf("trainhmm-%s", runtime.GOOS))); err == nil {
-		return
-	}
-	errors = append(errors, err)
-	if err = try(path.Join(mustUserHomeDir(), "standard-bkt", "trainhmm")); err == nil {
-		return
+	switch *bktFlag {
+	case "native":
+		// Parameters that a concept declares, or that were fitted on the
+		// data of a whole class, are not refitted on the answers of a
+		// single student, unless that is explicitly asked for. The fit
+		// then only starts from them.
+		fit := *bktFitFlag
+		if *paramsFlag != "" && !isFlagSet("bkt_fit") {
+			fit = false
+		}
+		backend = &nativeBKT{fit: fit, refit: fit && isFlagSet("bkt_fit")}
+	case "trainhmm":
+		backend = &trainhmmBKT{}
+	default:
+		panic(fmt.Sprintf("Unknown BKT backend: %s", *bktFlag))
 	}
-	errors = append(errors, err)
-	for _, err := range errors {
-		println(err.Error())
+	if err := backend.init(); err != nil {
+		panic(fmt.Sprintf("Cannot initialize BKT backend %s: %v", backend.getName(), err))
 	}
-	panic("Cannot find working trainhmm binary")
+}
+
+// isFlagSet checks if a flag was set on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
 // --------------------------------------------------------------------
@@ -80,9 +87,15 @@ func initBKT() {
 // answer is a struct that contains the information of an answered question.
 type answer struct {
 	questionShortName string // Here because of JSON unmarshaling.
+	subQuestionTag    string // Idem.
 	question          Question
 	subQuestion       subQuestion
 	correct           bool
+	time              time.Time     // When the answer was registered.
+	responses         []string      // All responses the student gave, the last one is correct.
+	duration          time.Duration // Time it took to answer.
+	instance          []string      // The references of the sub question instance.
+	seed              *int64        // Seed of the session the answer was given in, if known.
 }
 
 // studentState contains, guess what!
@@ -90,267 +103,116 @@ type studentState struct {
 	answers      []*answer
 	burnt        map[Question]interface{} // Set of burnt questions.
 	scores       map[*Concept]float64     // Knowledge scores per concept.
+	model        StudentModel             // Model of the student's knowledge.
 	content      *Content                 // Link to NITS content.
+	profile      *profile                 // Profile of the student.
 	nextQuestion Question                 // Allows the user to manually specify the next question.
+	askedAt      time.Time                // When the current (sub) question was asked.
+	seed         int64                    // Seed of the random number generator.
+	rng          *rand.Rand               // Source of all randomness in the session.
+	instance     *sqInstance              // The current sub question instance, if any.
+	unresolved   []*answer                // Loaded answers to questions that no longer exist.
+	backedUp     bool                     // Whether the backups were rotated in this session.
 }
 
 // newStudentState creates a new student state object.
-func newStudentState(content *Content) *studentState {
-	state := &studentState{content: content}
+func newStudentState(content *Content, model StudentModel) *studentState {
+	state := &studentState{content: content, model: model}
+	// Seeding from the clock is not cryptographically secure, but it's
+	// good enough for our purpose.
+	state.setSeed(time.Now().UnixNano())
 	state.reset()
 	return state
 }
 
+// setSeed seeds the random number generator of the session. All random
+// choices (questions, answer orders, case objects) come from it, so a
+// session with the same seed and the same answers asks the same questions.
+func (s *studentState) setSeed(seed int64) {
+	s.seed = seed
+	s.rng = rand.New(rand.NewSource(seed))
+}
+
 func (s *studentState) reset() {
 	s.answers = make([]*answer, 0)
 	s.burnt = make(map[Question]interface{})
 	s.scores = make(map[*Concept]float64)
+	s.unresolved = make([]*answer, 0)
+	s.model.reset()
 }
 
-// registerAnswer registers a new answer in the student state.
-func (s *studentState) registerAnswer(q Question, sq subQuestion, correct bool) {
-	s.answers = append(s.answers, &answer{
-		questionShortName: q.getShortName(),
-		question:          q,
-		subQuestion:       sq,
-		correct:           correct})
-	s.burn(q)
+// startQuestion registers the moment a (sub) question is asked.
+func (s *studentState) startQuestion() {
+	s.askedAt = time.Now()
+	s.instance = nil
 }
 
-// burn burns a question. It will not be asked again.
-func (s *studentState) burn(q Question) {
-	s.burnt[q] = nil
+// startInstance registers the moment an instance of a sub question is
+// asked. Answers are registered against the instance until the next
+// question is started.
+func (s *studentState) startInstance(inst *sqInstance) {
+	s.startQuestion()
+	s.instance = inst
 }
 
-// MarshalJson marshals an answer object to a JSON object.
-func (a *answer) MarshalJSON() ([]byte, error) {
-	m := make(map[string]interface{})
-	m["shortName"] = a.questionShortName
-	m["correct"] = a.correct
-	if a.subQuestion == nil {
-		m["subQuestion"] = ""
-	} else {
-		m["subQuestion"] = a.subQuestion.getTag()
-	}
-
-	return json.Marshal(m)
+// registerAnswer registers a new answer in the student state. The
+// responses are all the responses the student gave, the last of which
+// was correct. The answer counts as correct if the student got it right
+// the first time.
+func (s *studentState) registerAnswer(q Question, sq subQuestion, responses []string) {
+	s.registerOutcome(q, sq, responses, len(responses) == 1)
 }
 
-// UnmarshalJSON unmarshals a JSON object back to an answer.
-func (a *answer) UnmarshalJSON(b []byte) error {
-	m := make(map[string]interface{})
-	if err := json.Unmarshal(b, &m); err != nil {
-		return err
-	}
-	if v, ok := m["correct"].(bool); ok {
-		a.correct = v
-	} else {
-		return errors.New("data format error (correct)")
+// registerOutcome registers a new answer in the student state, for
+// questions where being correct is not just a matter of getting it right
+// the first time.
+func (s *studentState) registerOutcome(q Question, sq subQuestion, responses []string, correct bool) {
+	a := &answer{
+		questionShortName: q.getShortName(),
+		question:          q,
+		subQuestion:       sq,
+		correct:           correct,
+		time:              time.Now(),
+		responses:         responses,
 	}
-	if v, ok := m["shortName"].(string); ok {
-		// Since we do not have a link to the content here we store the
-		// question shortname. The caller will have to resolve that back
-		// to the interface.
-		a.questionShortName = v
-	} else {
-		return errors.New("data format error (shortName)")
+	if sq != nil {
+		a.subQuestionTag = sq.getTag()
 	}
-	if v, ok := m["subQuestion"].(string); ok {
-		if v == "" {
-			a.subQuestion = nil
-		} else if sq, ok := sqMap[v]; ok {
-			a.subQuestion = sq
-		}
-	} else {
-		return errors.New("data format error (subQuestion)")
+	if !s.askedAt.IsZero() {
+		a.duration = a.time.Sub(s.askedAt)
 	}
-
-	return nil
-}
-
-// saveUserData saves the student state to ~/.nits_data. Only the
-// registered answers are saved.
-func (s *studentState) saveUserData() error {
-	data, err := json.MarshalIndent(s.answers, "", "\t")
-	if err != nil {
-		return err
+	if s.instance != nil {
+		a.instance = s.instance.refs
 	}
-	return ioutil.WriteFile(path.Join(mustUserHomeDir(), ".nits_data"), data, 0644)
+	seed := s.seed
+	a.seed = &seed
+	s.answers = append(s.answers, a)
+	s.model.update(a)
+	s.burn(q)
+	s.autosave()
 }
 
-// loadUserData loads the student state from ~/.nits_data. Only the
-// registered answers are loaded. If a question/sub-question can not
-// be found the question is discarded.
-func (s *studentState) loadUserData() error {
-	data, err := ioutil.ReadFile(path.Join(mustUserHomeDir(), ".nits_data"))
-	if err != nil {
-		return err
-	}
-	answers := make([]*answer, 0)
-	s.burnt = make(map[Question]interface{})
-	if err := json.Unmarshal(data, &answers); err != nil {
-		return err
-	}
-
-	// We now need to find the questions in the content by the short name
-	// that unMarshalJSON has put there.
-	for _, a := range answers {
-		// There is a chance that we are not finding the question if either
-		// the student data has been manipulated (manual testing) or if the
-		// question database has changed and a question has been removed.
-		// Since we have already loaded the record we are going to keep it,
-		// but when saving the student state we will drop it since there
-		// is nothing we can do with it (the concepts have been lost).
-		a.question = s.content.findQuestion(a.questionShortName)
-
-		if a.question != nil {
-			// If the question is a case and the sub question is nil then
-			// drop this question because the sub question has apparently
-			// been removed from the code.
-			if c, ok := a.question.(*Case); ok && a.subQuestion == nil {
-				if trace != nil {
-					trace.println("Discarding %s because sq not found", c.ShortName)
-				}
-				a.question = nil
-			}
-		}
-	}
-
-	// Copy all the successfully loaded questions to the state.
-	s.answers = make([]*answer, 0, len(answers))
-	for _, a := range answers {
-		if a.question != nil {
-			s.answers = append(s.answers, a)
-			s.burnt[a.question] = nil
-		} else if trace != nil {
-			trace.println("Discarding a question.")
-		}
-	}
-
-	return nil
+// burn burns a question. It will not be asked again.
+func (s *studentState) burn(q Question) {
+	s.burnt[q] = nil
 }
 
 // --------------------------------------------------------------------
 
-// writeTrainhmmInput writes the input file for the trainhmm binary.
-// It returns the name of the temporary directory where the file was
-// written. The format of the input file is described here:
-// https://iedms.github.io/standard-bkt/.
-func (s *studentState) writeTrainhmmInput() (string, error) {
-	td, err := ioutil.TempDir("", "nits*")
-	if err != nil {
-		return td, err
-	}
-	var buffer bytes.Buffer
-
-	// One line per registered answer.
-	for _, a := range s.answers {
-		columns := make([]string, 0)
-		if a.correct {
-			columns = append(columns, correct)
-		} else {
-			columns = append(columns, incorrect)
-		}
-		var tag string
-		if a.subQuestion == nil {
-			tag = a.questionShortName
-		} else {
-			tag = fmt.Sprintf("%s#%s", a.questionShortName, a.subQuestion.getTag())
-		}
-		columns = append(columns, "student", tag)
-		names := make([]string, 0)
-		for _, c := range a.question.getTrainingConcepts(a.subQuestion) {
-			names = append(names, c.shortName)
-		}
-		columns = append(columns, strings.Join(names, separator))
-		_, err := buffer.WriteString(strings.Join(columns, "\t"))
-		if err != nil {
-			return td, err
-		}
-		_, err = buffer.WriteRune('\n')
-		if err != nil {
-			return td, err
-		}
-	}
-
-	err = ioutil.WriteFile(path.Join(td, "input"), buffer.Bytes(), 0644)
-	return td, err
-}
-
-// runTrainhmm runs the trainhmm binary.
-func (s *studentState) runTrainhmm(td string) error {
-	cmd := exec.Command(
-		trainhmmPath,
-		"-p", "2",
-		"-0", fmt.Sprintf("%f,1.0,%f,%f,%f", pInit, pLearn, 1-pSlip, pGuess),
-		"-d", separator,
-		path.Join(td, "input"),
-		path.Join(td, "model.txt"),
-		path.Join(td, "predict.txt"),
-	)
-	_, err := cmd.Output()
-	return err
-}
-
-// readPrediction reads the skill scores from the prediction file
-// generated by trainhmm.
-func (s *studentState) readPrediction(td string) error {
-	file, err := os.Open(path.Join(td, "predict.txt"))
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
-
-	for _, a := range s.answers {
-		if a.question == nil {
-			continue
-		}
-		if !scanner.Scan() {
-			return errors.New("unexpected end of predict.txt")
-		}
-		words := strings.Split(scanner.Text(), "\t")
-		i := 2
-		d, err := strconv.ParseFloat(words[0], 64)
-		if err != nil {
-			return err
-		}
-		// If this column contains 1.0 there is no next column, otherwise
-		// the next column contains 1.0-d.
-		if d == 1.0 {
-			i = 1
-		}
-		for _, c := range a.question.getTrainingConcepts(a.subQuestion) {
-			d, err := strconv.ParseFloat(words[i], 64)
-			if err != nil {
-				return err
-			}
-			s.scores[c] = d
-		}
-	}
-
-	return scanner.Err()
-}
-
-// train runs the trainhmm binary on the questions answered by the
-// student and reads back the skill scores into the state.
+// train asks the student model for its predictions and stores the
+// resulting skill scores in the state.
 func (s *studentState) train() error {
 	if len(s.answers) == 0 {
 		return nil
 	}
-	td, err := s.writeTrainhmmInput()
+	scores, err := s.model.predict()
 	if err != nil {
 		return err
 	}
-	err = s.runTrainhmm(td)
-	if err != nil {
-		return err
-	}
-	err = s.readPrediction(td)
-	if err != nil {
-		return err
+	for c, score := range scores {
+		s.scores[c] = score
 	}
-	return os.RemoveAll(td)
+	return nil
 }
 
 // --------------------------------------------------------------------
@@ -0,0 +1,180 @@
+package nits
+
+// This file contains a native implementation of Bayesian Knowledge
+// Tracing: the forward algorithm to trace a student's knowledge and
+// Baum-Welch (EM) to fit the model parameters. It follows the standard
+// BKT model, which is a two state hidden Markov model (concept not known,
+// concept known) without forgetting.
+
+import "math"
+
+const (
+	fitIterations = 100   // Maximum number of EM iterations.
+	fitEpsilon    = 1e-6  // EM stops when the log likelihood improves less than this.
+	minProb       = 0.001 // Lower bound for every fitted parameter.
+	maxProb       = 0.999 // Upper bound for pInit and pLearn.
+	maxSlipGuess  = 0.5   // Upper bound for pSlip and pGuess, to keep the model identifiable.
+)
+
+// nativeBKT is the BKT backend that runs in process.
+type nativeBKT struct {
+	fit   bool // If true, fit the parameters on the student's answers before tracing.
+	refit bool // If true, also fit the parameters that a concept declares.
+}
+
+func (n *nativeBKT) getName() string {
+	return "native"
+}
+
+func (n *nativeBKT) init() error {
+	return nil
+}
+
+// observations collects the sequence of observations (correct or not) per
+// concept from a slice of answers.
+func observations(answers []*answer) map[*Concept][]bool {
+	seqs := make(map[*Concept][]bool)
+	for _, a := range answers {
+		if a.question == nil {
+			continue
+		}
+		for _, c := range a.question.getTrainingConcepts(a.subQuestion) {
+			seqs[c] = append(seqs[c], a.correct)
+		}
+	}
+	return seqs
+}
+
+// train computes the skill scores for all concepts in the answers.
+func (n *nativeBKT) train(answers []*answer) (map[*Concept]float64, error) {
+	scores := make(map[*Concept]float64)
+	for c, obs := range observations(answers) {
+		params := c.getBKTParams()
+		if n.fit && (c.bkt == nil || n.refit) {
+			params, _ = params.fit([][]bool{obs})
+		}
+		scores[c] = params.trace(obs)
+	}
+	return scores, nil
+}
+
+// --------------------------------------------------------------------
+
+// emit returns the probability of an observation given the state.
+func (p bktParams) emit(known, obs bool) float64 {
+	if known {
+		if obs {
+			return 1 - p.pSlip
+		}
+		return p.pSlip
+	}
+	if obs {
+		return p.pGuess
+	}
+	return 1 - p.pGuess
+}
+
+// trace runs the forward algorithm over a sequence of observations and
+// returns the probability that the concept is known after the last one.
+func (p bktParams) trace(obs []bool) float64 {
+	pL := p.pInit
+	for _, o := range obs {
+		known := pL * p.emit(true, o)
+		post := known / (known + (1-pL)*p.emit(false, o))
+		pL = post + (1-post)*p.pLearn
+	}
+	return pL
+}
+
+// clamp limits a probability to the interval [lo, hi].
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// fit fits the parameters to a set of observation sequences with the
+// Baum-Welch algorithm, using the receiver as the starting point. It
+// returns the fitted parameters and their log likelihood.
+func (p bktParams) fit(seqs [][]bool) (bktParams, float64) {
+	ll := math.Inf(-1)
+	for i := 0; i < fitIterations; i++ {
+		next, nll := p.step(seqs)
+		if nll-ll < fitEpsilon {
+			return p, math.Max(ll, nll)
+		}
+		p, ll = next, nll
+	}
+	return p, ll
+}
+
+// step does a single EM iteration. It returns the re-estimated parameters
+// and the log likelihood of the sequences under the current parameters.
+func (p bktParams) step(seqs [][]bool) (bktParams, float64) {
+	var initKnown, n, learned, unknown, guessed, unknownAll, slipped, knownAll, ll float64
+
+	for _, obs := range seqs {
+		T := len(obs)
+		if T == 0 {
+			continue
+		}
+		// Scaled forward pass. alpha[t][0] is "unknown", alpha[t][1] is "known".
+		alpha := make([][2]float64, T)
+		scale := make([]float64, T)
+		for t, o := range obs {
+			if t == 0 {
+				alpha[t][0] = (1 - p.pInit) * p.emit(false, o)
+				alpha[t][1] = p.pInit * p.emit(true, o)
+			} else {
+				alpha[t][0] = alpha[t-1][0] * (1 - p.pLearn) * p.emit(false, o)
+				alpha[t][1] = (alpha[t-1][0]*p.pLearn + alpha[t-1][1]) * p.emit(true, o)
+			}
+			scale[t] = alpha[t][0] + alpha[t][1]
+			alpha[t][0] /= scale[t]
+			alpha[t][1] /= scale[t]
+			ll += math.Log(scale[t])
+		}
+		// Scaled backward pass.
+		beta := make([][2]float64, T)
+		beta[T-1] = [2]float64{1, 1}
+		for t := T - 2; t >= 0; t-- {
+			o := obs[t+1]
+			beta[t][0] = ((1-p.pLearn)*p.emit(false, o)*beta[t+1][0] + p.pLearn*p.emit(true, o)*beta[t+1][1]) / scale[t+1]
+			beta[t][1] = p.emit(true, o) * beta[t+1][1] / scale[t+1]
+		}
+		// Accumulates the expected counts.
+		for t, o := range obs {
+			g0 := alpha[t][0] * beta[t][0]
+			g1 := alpha[t][1] * beta[t][1]
+			g0, g1 = g0/(g0+g1), g1/(g0+g1)
+			if t == 0 {
+				initKnown += g1
+				n++
+			}
+			if t < T-1 {
+				learned += alpha[t][0] * p.pLearn * p.emit(true, obs[t+1]) * beta[t+1][1] / scale[t+1]
+				unknown += g0
+			}
+			unknownAll += g0
+			knownAll += g1
+			if o {
+				guessed += g0
+			} else {
+				slipped += g1
+			}
+		}
+	}
+
+	next := p
+	if n > 0 {
+		next.pInit = clamp(initKnown/n, minProb, maxProb)
+	}
+	if unknown > 0 {
+		next.pLearn = clamp(learned/unknown, minProb, maxProb)
+	}
+	if unknownAll > 0 {
+		next.pGuess = clamp(guessed/unknownAll, minProb, maxSlipGuess)
+	}
+	if knownAll > 0 {
+		next.pSlip = clamp(slipped/knownAll, minProb, maxSlipGuess)
+	}
+	return next, ll
+}
@@ -0,0 +1,99 @@
+package nits
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	p := defaultBKTParams
+	if got := p.trace(nil); got != pInit {
+		t.Errorf("trace(nil); got:%f, want:%f", got, pInit)
+	}
+	prev := p.trace(nil)
+	obs := make([]bool, 0)
+	for i := 0; i < 10; i++ {
+		obs = append(obs, true)
+		got := p.trace(obs)
+		if got <= prev {
+			t.Errorf("trace(%d correct); got:%f, want more than %f", len(obs), got, prev)
+		}
+		prev = got
+	}
+	if prev < threshold {
+		t.Errorf("trace(10 correct); got:%f, want at least %f", prev, threshold)
+	}
+	if got := p.trace([]bool{false}); got >= p.trace([]bool{true}) {
+		t.Errorf("trace([false]); got:%f, want less than trace([true])", got)
+	}
+}
+
+func TestFit(t *testing.T) {
+	// Generates sequences from known parameters.
+	want := bktParams{pInit: 0.3, pLearn: 0.15, pSlip: 0.1, pGuess: 0.2}
+	r := rand.New(rand.NewSource(1))
+	seqs := make([][]bool, 0)
+	for i := 0; i < 500; i++ {
+		known := r.Float64() < want.pInit
+		obs := make([]bool, 0)
+		for j := 0; j < 20; j++ {
+			if known {
+				obs = append(obs, r.Float64() >= want.pSlip)
+			} else {
+				obs = append(obs, r.Float64() < want.pGuess)
+				known = r.Float64() < want.pLearn
+			}
+		}
+		seqs = append(seqs, obs)
+	}
+
+	_, before := defaultBKTParams.step(seqs)
+	got, after := defaultBKTParams.fit(seqs)
+	if after < before {
+		t.Errorf("fit decreased the log likelihood; before:%f, after:%f", before, after)
+	}
+	for _, v := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"pInit", got.pInit, want.pInit},
+		{"pLearn", got.pLearn, want.pLearn},
+		{"pSlip", got.pSlip, want.pSlip},
+		{"pGuess", got.pGuess, want.pGuess},
+	} {
+		if v.got < v.want-0.05 || v.got > v.want+0.05 {
+			t.Errorf("fit: %s; got:%f, want:%f", v.name, v.got, v.want)
+		}
+	}
+}
+
+func TestConceptParams(t *testing.T) {
+	c := &Concept{shortName: "test", bkt: &conceptBKT{pGuess: prob(0.1), pLearn: prob(0)}}
+	want := defaultBKTParams
+	want.pGuess = 0.1
+	want.pLearn = 0
+	if got := c.getBKTParams(); got != want {
+		t.Errorf("getBKTParams(); got:%v, want:%v", got, want)
+	}
+	if got := (&Concept{}).getBKTParams(); got != defaultBKTParams {
+		t.Errorf("getBKTParams() without parameters; got:%v, want:%v", got, defaultBKTParams)
+	}
+
+	// Declared parameters are only refitted if that is asked for.
+	q := &MultipleChoiceQuestion{ShortName: "mc_test", Concepts: []*Concept{c}}
+	answers := make([]*answer, 0)
+	obs := make([]bool, 0)
+	for _, correct := range []bool{false, true, true, false, true, true} {
+		answers = append(answers, &answer{question: q, correct: correct})
+		obs = append(obs, correct)
+	}
+	scores, _ := (&nativeBKT{fit: true}).train(answers)
+	if want := want.trace(obs); scores[c] != want {
+		t.Errorf("train() with declared parameters; got:%f, want:%f", scores[c], want)
+	}
+	fitted, _ := want.fit([][]bool{obs})
+	scores, _ = (&nativeBKT{fit: true, refit: true}).train(answers)
+	if want := fitted.trace(obs); scores[c] != want {
+		t.Errorf("train() with refit; got:%f, want:%f", scores[c], want)
+	}
+}
@@ -0,0 +1,224 @@
+package nits
+
+// This file contains the BKT backend that runs the trainhmm binary from
+// the standard-bkt tool (https://iedms.github.io/standard-bkt/). It is
+// mostly useful to cross-check the native implementation.
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	correct   = "1" // Indicator for trainhmm.
+	incorrect = "2" // Indicator for trainhmm.
+	separator = "~" // Separator for the concepts in the input file for trainhmm.
+)
+
+// trainhmmBKT is the BKT backend that shells out to trainhmm.
+type trainhmmBKT struct {
+	path string // Path to the trainhmm binary
+}
+
+func (t *trainhmmBKT) getName() string {
+	return "trainhmm"
+}
+
+// try tries a possible hmmPath and returns an error if this is certainly not
+// the trainhmm binary we want.
+func (t *trainhmmBKT) try(hmmPath string) error {
+	fi, err := os.Stat(hmmPath)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeType != 0 {
+		return errors.New(fmt.Sprintf("%s: illegal file type", hmmPath))
+	}
+	if fi.Mode()&0555 != 0555 {
+		return errors.New(fmt.Sprintf("%s: illegal access mode (executable?)", hmmPath))
+	}
+	println("HMM training binary is in", hmmPath)
+	t.path = hmmPath
+	return nil
+}
+
+// init finds the trainhmm binary.
+func (t *trainhmmBKT) init() error {
+	var err error
+	errs := make([]error, 0)
+	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
+	if err != nil {
+		return errors.New("can't determine the directory where the executable lives")
+	}
+	if err = t.try(path.Join(dir, fmt.Sprintf("trainhmm-%s", runtime.GOOS))); err == nil {
+		return nil
+	}
+	errs = append(errs, err)
+	if err = t.try(path.Join(mustUserHomeDir(), "standard-bkt", "trainhmm")); err == nil {
+		return nil
+	}
+	errs = append(errs, err)
+	for _, err := range errs {
+		println(err.Error())
+	}
+	return errors.New("cannot find working trainhmm binary")
+}
+
+// trainhmmRow is a row in the input file for trainhmm.
+type trainhmmRow struct {
+	answer   *answer
+	concepts []*Concept
+}
+
+// groupByParams groups the concepts in a set of answers by their BKT
+// parameters. trainhmm takes one set of starting parameters per run, so
+// every group needs a separate run.
+func groupByParams(answers []*answer) map[bktParams][]*trainhmmRow {
+	groups := make(map[bktParams][]*trainhmmRow)
+	for _, a := range answers {
+		if a.question == nil {
+			continue
+		}
+		rows := make(map[bktParams]*trainhmmRow)
+		for _, c := range a.question.getTrainingConcepts(a.subQuestion) {
+			p := c.getBKTParams()
+			if rows[p] == nil {
+				rows[p] = &trainhmmRow{answer: a}
+				groups[p] = append(groups[p], rows[p])
+			}
+			rows[p].concepts = append(rows[p].concepts, c)
+		}
+	}
+	return groups
+}
+
+// writeInput writes the input file for the trainhmm binary.
+// It returns the name of the temporary directory where the file was
+// written. The format of the input file is described here:
+// https://iedms.github.io/standard-bkt/.
+func (t *trainhmmBKT) writeInput(rows []*trainhmmRow) (string, error) {
+	td, err := ioutil.TempDir("", "nits*")
+	if err != nil {
+		return td, err
+	}
+	var buffer bytes.Buffer
+
+	// One line per registered answer.
+	for _, row := range rows {
+		a := row.answer
+		columns := make([]string, 0)
+		if a.correct {
+			columns = append(columns, correct)
+		} else {
+			columns = append(columns, incorrect)
+		}
+		var tag string
+		if a.subQuestion == nil {
+			tag = a.questionShortName
+		} else {
+			tag = fmt.Sprintf("%s#%s", a.questionShortName, a.subQuestion.getTag())
+		}
+		columns = append(columns, "student", tag)
+		names := make([]string, 0)
+		for _, c := range row.concepts {
+			names = append(names, c.shortName)
+		}
+		columns = append(columns, strings.Join(names, separator))
+		_, err := buffer.WriteString(strings.Join(columns, "\t"))
+		if err != nil {
+			return td, err
+		}
+		_, err = buffer.WriteRune('\n')
+		if err != nil {
+			return td, err
+		}
+	}
+
+	err = ioutil.WriteFile(path.Join(td, "input"), buffer.Bytes(), 0644)
+	return td, err
+}
+
+// run runs the trainhmm binary with a set of starting parameters.
+func (t *trainhmmBKT) run(td string, p bktParams) error {
+	cmd := exec.Command(
+		t.path,
+		"-p", "2",
+		"-0", fmt.Sprintf("%f,1.0,%f,%f,%f", p.pInit, p.pLearn, 1-p.pSlip, p.pGuess),
+		"-d", separator,
+		path.Join(td, "input"),
+		path.Join(td, "model.txt"),
+		path.Join(td, "predict.txt"),
+	)
+	_, err := cmd.Output()
+	return err
+}
+
+// readPrediction reads the skill scores from the prediction file
+// generated by trainhmm into the scores map.
+func (t *trainhmmBKT) readPrediction(td string, rows []*trainhmmRow, scores map[*Concept]float64) error {
+	file, err := os.Open(path.Join(td, "predict.txt"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+
+	for _, row := range rows {
+		if !scanner.Scan() {
+			return errors.New("unexpected end of predict.txt")
+		}
+		words := strings.Split(scanner.Text(), "\t")
+		i := 2
+		d, err := strconv.ParseFloat(words[0], 64)
+		if err != nil {
+			return err
+		}
+		// If this column contains 1.0 there is no next column, otherwise
+		// the next column contains 1.0-d.
+		if d == 1.0 {
+			i = 1
+		}
+		for _, c := range row.concepts {
+			d, err := strconv.ParseFloat(words[i], 64)
+			if err != nil {
+				return err
+			}
+			scores[c] = d
+		}
+	}
+
+	return scanner.Err()
+}
+
+// train runs the trainhmm binary on the answers and reads back the skill
+// scores. It runs trainhmm once for every distinct set of BKT parameters
+// of the concepts involved.
+func (t *trainhmmBKT) train(answers []*answer) (map[*Concept]float64, error) {
+	scores := make(map[*Concept]float64)
+	for p, rows := range groupByParams(answers) {
+		td, err := t.writeInput(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.run(td, p); err != nil {
+			return nil, err
+		}
+		if err := t.readPrediction(td, rows, scores); err != nil {
+			return nil, err
+		}
+		if err := os.RemoveAll(td); err != nil {
+			return nil, err
+		}
+	}
+	return scores, nil
+}
@@ -2,7 +2,13 @@ package nits
 
 // This file contains code related to answering cases.
 
-import "math/rand"
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
 
 // --------------------------------------------------------------------
 
@@ -11,6 +17,15 @@ type Case struct {
 	Text []string
 	ShortName  string
 	RootEvents []Event
+	Jurisdiction *Jurisdiction
+	Relationships []*Relationship
+	// SubQuestions limits the sub questions that can be asked about this
+	// case to the ones with these tags. If empty, all sub questions that
+	// apply to the case can be asked.
+	SubQuestions []string
+	// ExcludedSubQuestions are the tags of sub questions that are never
+	// asked about this case.
+	ExcludedSubQuestions []string
 	preproc *preprocessedCase
 }
 
@@ -19,7 +34,53 @@ type Case struct {
 type subQuestion interface {
 	getTag() string
 	getConcepts() []*Concept
-	ask(*Case, *userInterface, *studentState) bool
+	// applies tells if the sub question can be asked about a case.
+	applies(*preprocessedCase) bool
+	// newInstance picks the objects in a case to ask about, or returns nil
+	// if there is nothing to ask.
+	newInstance(*preprocessedCase, *rand.Rand) *sqInstance
+	ask(*Case, *userInterface, *studentState, *sqInstance) bool
+}
+
+// sqInstance is an instance of a sub question: the sub question together
+// with the objects in the case it asks about. The objects are referred to
+// by their ids, so that the instance can be saved with the answer and be
+// asked again later. Choices that only serve as distractors are not part
+// of the instance.
+type sqInstance struct {
+	sq   subQuestion
+	refs []string
+}
+
+func (i *sqInstance) String() string {
+	return fmt.Sprintf("%s(%s)", i.sq.getTag(), strings.Join(i.refs, ", "))
+}
+
+// newInstance makes an instance of a sub question about some objects in a
+// case.
+func (p *preprocessedCase) newInstance(sq subQuestion, objs ...identifiable) *sqInstance {
+	refs := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		refs = append(refs, p.id(obj))
+	}
+	return &sqInstance{sq: sq, refs: refs}
+}
+
+// ref returns the object that the i'th reference of an instance refers to,
+// or nil if there is no such object.
+func (p *preprocessedCase) ref(inst *sqInstance, i int) identifiable {
+	if i >= len(inst.refs) {
+		return nil
+	}
+	return p.objects[inst.refs[i]]
+}
+
+// badInstance tells the user that an instance does not fit the case. This
+// can only happen with instances that were saved for an older version of
+// the case.
+func badInstance(ui *userInterface, inst *sqInstance) bool {
+	ui.error("The instance %s does not fit this case.", inst)
+	return false
 }
 
 // sqMap is a global map of sub question types.
@@ -32,18 +93,110 @@ func addSubQuestion(sq subQuestion) subQuestion {
 	return sq
 }
 
+// stepMap is a global map of sub questions that are only asked as a step
+// of another sub question. They are never selected on their own, but
+// answers are registered against them.
+var stepMap = make(map[string]*step)
+
+// addStep is a method for registering a step in the global map.
+func addStep(s *step) *step {
+	stepMap[s.tag] = s
+	return s
+}
+
+// step is a step of a sub question. It only has a tag and concepts; the
+// sub question it belongs to does the asking.
+type step struct {
+	parent   string // The tag of the sub question the step belongs to.
+	tag      string
+	concepts []*Concept
+	// inCase tells if the step can be asked about a case. If nil, the
+	// step is part of every case its sub question is asked about.
+	inCase func(*preprocessedCase) bool
+}
+
+func (s *step) getTag() string {
+	return s.tag
+}
+
+func (s *step) getConcepts() []*Concept {
+	return s.concepts
+}
+
+// applies returns false: steps are never selected on their own.
+func (s *step) applies(*preprocessedCase) bool {
+	return false
+}
+
+func (s *step) newInstance(*preprocessedCase, *rand.Rand) *sqInstance {
+	return nil
+}
+
+func (s *step) ask(*Case, *userInterface, *studentState, *sqInstance) bool {
+	panic("steps are only asked by the sub question they belong to")
+}
+
+// lookupSubQuestion finds a sub question or step by its tag.
+func lookupSubQuestion(tag string) subQuestion {
+	if sq, ok := sqMap[tag]; ok {
+		return sq
+	}
+	if s, ok := stepMap[tag]; ok {
+		return s
+	}
+	return nil
+}
+
 func (c *Case) getShortName() string {
 	return c.ShortName
 }
 
-// getConcepts returns all the concepts in a case. Right now it returns all
-// the concepts involved in all the sub questions. This is not entirely true
-// because some sub questions might not apply to all cases. However at this
-// time we do not yet have the machinery to make this distinction.
+// subQuestions returns the sub questions that can be asked about a case:
+// the ones that apply to it and that are not excluded by the case. They are
+// sorted by tag, so that the order does not depend on map iteration.
+func (c *Case) subQuestions() []subQuestion {
+	pp := c.preprocess()
+	only := make(map[string]bool, len(c.SubQuestions))
+	for _, tag := range c.SubQuestions {
+		only[tag] = true
+	}
+	excluded := make(map[string]bool, len(c.ExcludedSubQuestions))
+	for _, tag := range c.ExcludedSubQuestions {
+		excluded[tag] = true
+	}
+	result := make([]subQuestion, 0, len(sqMap))
+	for tag, sq := range sqMap {
+		if (len(only) > 0 && !only[tag]) || excluded[tag] || !sq.applies(pp) {
+			continue
+		}
+		result = append(result, sq)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].getTag() < result[j].getTag()
+	})
+	return result
+}
+
+// sqConcepts returns the concepts that asking a sub question about a case
+// trains: the concepts of the sub question and of the steps of it that can
+// be asked about the case.
+func (c *Case) sqConcepts(sq subQuestion) []*Concept {
+	pp := c.preprocess()
+	result := append([]*Concept(nil), sq.getConcepts()...)
+	for _, s := range stepMap {
+		if s.parent == sq.getTag() && (s.inCase == nil || s.inCase(pp)) {
+			result = append(result, s.concepts...)
+		}
+	}
+	return result
+}
+
+// getConcepts returns all the concepts in a case: the concepts of the sub
+// questions that can be asked about it and of their steps.
 func (c *Case) getConcepts() []*Concept {
 	result := make(map[*Concept]interface{}, 0)
-	for _, sq := range sqMap {
-		for _, c := range sq.getConcepts() {
+	for _, sq := range c.subQuestions() {
+		for _, c := range c.sqConcepts(sq) {
 			result[c] = nil
 		}
 	}
@@ -52,6 +205,10 @@ func (c *Case) getConcepts() []*Concept {
 	for c, _ := range result {
 		ret = append(ret, c)
 	}
+	// Sorted, so that the concepts do not come out in map order.
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].shortName < ret[j].shortName
+	})
 
 	return ret
 }
@@ -63,12 +220,15 @@ func (c *Case) getTrainingConcepts(sq subQuestion) []*Concept {
 		return c.getConcepts()
 	}
 
-	return sqMap[sq.getTag()].getConcepts()
+	return sq.getConcepts()
 }
 
-// check checks the validity of a case. Not implemented at this time.
+// check checks the validity of a case: its graph must be free of
+// structural problems.
 func (c *Case) check() {
-	c.preprocess()
+	if err := c.preprocess().err(); err != nil {
+		panic(err.Error())
+	}
 }
 
 // pushSubQuestionCommandContext pushes a command context on the stack
@@ -95,8 +255,8 @@ func (c *Case) selectSubQuestion(state *studentState, done map[subQuestion]int)
 
 	possibles := make([]subQuestion, 0)
 
-	for _, sq := range sqMap {
-		nm := state.conceptsNotMastered(sq.getConcepts())
+	for _, sq := range c.subQuestions() {
+		nm := state.conceptsNotMastered(c.sqConcepts(sq))
 		if len(nm) > 0 && done[sq] < 2 {
 			possibles = append(possibles, sq)
 		}
@@ -106,15 +266,16 @@ func (c *Case) selectSubQuestion(state *studentState, done map[subQuestion]int)
 		return nil
 	}
 
-	sq := possibles[rand.Int() % len(possibles)]
+	sq := possibles[state.rng.Intn(len(possibles))]
 	if trace != nil {
 		trace.println("Returning sub question: %s", sq.getTag())
 	}
 	return sq
 }
 
-// ask asks a case question. It will ask sub questions until they are exhausted.
-func (c *Case) ask(ui *userInterface, state *studentState) {
+// withContext displays a case and runs a function with the case as the
+// command context.
+func (c *Case) withContext(ui *userInterface, state *studentState, f func()) {
 	displayCase := func([]string) bool {
 		ui.printParagraphs(c.Text)
 		ui.newline()
@@ -127,20 +288,58 @@ func (c *Case) ask(ui *userInterface, state *studentState) {
 	defer ui.popCommandContext()
 	defer ui.popPrompt()
 
-	// Map that keeps track of how often we have already asked a sub question.
-	done := make(map[subQuestion]int)
+	f()
+}
+
+// ask asks a case question. It will ask sub questions until they are exhausted.
+func (c *Case) ask(ui *userInterface, state *studentState) {
+	c.withContext(ui, state, func() {
+		// Map that keeps track of how often we have already asked a sub question.
+		done := make(map[subQuestion]int)
 
-	for {
-		sq := c.selectSubQuestion(state, done)
-		if sq == nil {
-			ui.println("Nothing left to ask in this case.")
-			return
+		for {
+			sq := c.selectSubQuestion(state, done)
+			if sq == nil {
+				ui.println("Nothing left to ask in this case.")
+				return
+			}
+			done[sq]++
+			inst := sq.newInstance(c.preprocess(), state.rng)
+			if inst == nil {
+				continue
+			}
+			if trace != nil {
+				trace.println("Asking instance: %s", inst)
+			}
+			if c.askInstance(ui, state, inst) {
+				return
+			}
 		}
-		ret := sq.ask(c, ui, state)
-		if ret {
-			return
+	})
+}
+
+// askInstance asks an instance of a sub question.
+func (c *Case) askInstance(ui *userInterface, state *studentState, inst *sqInstance) bool {
+	state.startInstance(inst)
+	return inst.sq.ask(c, ui, state, inst)
+}
+
+// findInstance finds the instance of a sub question with the given
+// references. Answers to the steps of a sub question are answers to the
+// instance of the sub question itself.
+func (c *Case) findInstance(sq subQuestion, refs []string) (*sqInstance, error) {
+	if s, ok := sq.(*step); ok {
+		sq = lookupSubQuestion(s.parent)
+	}
+	if sq == nil || len(refs) == 0 {
+		return nil, errors.New("no instance")
+	}
+	pp := c.preprocess()
+	for _, id := range refs {
+		if _, ok := pp.objects[id]; !ok {
+			return nil, fmt.Errorf("no object %s in case %s", id, c.ShortName)
 		}
-		done[sq]++
 	}
+	return &sqInstance{sq: sq, refs: refs}, nil
 }
 
@@ -0,0 +1,171 @@
+package nits
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSubQuestions(t *testing.T) {
+	tags := func(c *Case) map[string]bool {
+		m := make(map[string]bool)
+		for _, sq := range c.subQuestions() {
+			m[sq.getTag()] = true
+		}
+		return m
+	}
+
+	c := DefaultCase()
+	got := tags(c)
+	if !got["duty"] || !got["primaFacie"] {
+		t.Errorf("subQuestions(); got: %v, want duty and primaFacie", got)
+	}
+	if got["resIpsa"] {
+		t.Errorf("subQuestions(); got: resIpsa, which does not apply")
+	}
+	concepts := make(map[*Concept]bool)
+	all := c.getConcepts()
+	for i, concept := range all {
+		concepts[concept] = true
+		if i > 0 && all[i-1].shortName >= concept.shortName {
+			t.Errorf("getConcepts(); got: %s before %s, want them sorted by short name", all[i-1].shortName, concept.shortName)
+		}
+	}
+	if concepts[ResIpsaLoquitur1] || !concepts[InjuryOrDamage0] {
+		t.Errorf("getConcepts(); got: %v, want the concepts of the applicable sub questions and their steps", concepts)
+	}
+	// The default case is tried in Texas, so only the apportionment step
+	// for modified comparative negligence is part of it.
+	if concepts[PureComparativeNegligence1] || !concepts[ModifiedComparativeNegligence1] || !concepts[ComparativeNegligence1] {
+		t.Errorf("getConcepts(); got: %v, want only the concepts of the negligence rule of the case", concepts)
+	}
+
+	c.ExcludedSubQuestions = []string{"duty"}
+	if tags(c)["duty"] {
+		t.Errorf("subQuestions() with duty excluded; got: duty")
+	}
+	c.ExcludedSubQuestions = nil
+	c.SubQuestions = []string{"duty", "resIpsa"}
+	if got := tags(c); len(got) != 1 || !got["duty"] {
+		t.Errorf("subQuestions() with a whitelist; got: %v, want: duty", got)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	c := DefaultCase()
+	pp := c.preprocess()
+	for _, sq := range c.subQuestions() {
+		inst := sq.newInstance(pp, rand.New(rand.NewSource(1)))
+		if inst == nil || len(inst.refs) == 0 {
+			t.Errorf("newInstance() for %s; got: %v, want: an instance", sq.getTag(), inst)
+			continue
+		}
+		// The same seed gives the same instance, also for a fresh copy of
+		// the case.
+		if again := sq.newInstance(DefaultCase().preprocess(), rand.New(rand.NewSource(1))); again.String() != inst.String() {
+			t.Errorf("newInstance() for %s with the same seed; got: %s and %s", sq.getTag(), inst, again)
+		}
+		for i := range inst.refs {
+			if pp.ref(inst, i) == nil {
+				t.Errorf("newInstance() for %s; got: %s, which refers to an unknown object", sq.getTag(), inst)
+			}
+		}
+		found, err := c.findInstance(sq, inst.refs)
+		if err != nil || found.String() != inst.String() {
+			t.Errorf("findInstance(%s); got: %v, %v, want: %s", sq.getTag(), found, err, inst)
+		}
+	}
+
+	// Answers to a step belong to the instance of the sub question.
+	inst, err := c.findInstance(primaFacieDuty, []string{"rooke"})
+	if err != nil || inst.sq.getTag() != "primaFacie" {
+		t.Errorf("findInstance(primaFacie.duty); got: %v, %v, want: a primaFacie instance", inst, err)
+	}
+	if _, err := c.findInstance(sqMap["duty"], []string{"nosuchduty"}); err == nil {
+		t.Errorf("findInstance() with an unknown object; want: error")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	// order returns the first instances that are asked about the default
+	// case in a session with a seed.
+	useBackend(t, &nativeBKT{})
+	order := func(seed int64) []string {
+		model, _ := newStudentModel("bkt")
+		c := DefaultCase()
+		state := newStudentState(&Content{Questions: []Question{c}}, model)
+		state.setSeed(seed)
+		done := make(map[subQuestion]int)
+		result := make([]string, 0)
+		for i := 0; i < 10; i++ {
+			sq := c.selectSubQuestion(state, done)
+			if sq == nil {
+				break
+			}
+			done[sq]++
+			result = append(result, sq.newInstance(c.preprocess(), state.rng).String())
+		}
+		return result
+	}
+
+	first, second := order(42), order(42)
+	if len(first) == 0 || strings.Join(first, " ") != strings.Join(second, " ") {
+		t.Errorf("order(42) twice; got: %v and %v, want: the same instances", first, second)
+	}
+}
+
+func TestReaskDoesNotRegister(t *testing.T) {
+	useBackend(t, &nativeBKT{})
+	c := DefaultCase()
+	c.Text = nil
+	model, _ := newStudentModel("bkt")
+	state := newStudentState(&Content{Questions: []Question{c}}, model)
+	state.setSeed(1)
+	sq := sqMap["duty"]
+	inst := sq.newInstance(c.preprocess(), state.rng)
+	state.answers = append(state.answers, &answer{question: c, subQuestion: sq, instance: inst.refs})
+
+	lines, err := readScript("testdata/duty.script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	reask(newScriptedUserInterface(lines, &out), state, []string{"reask", "1"})
+	if len(state.answers) != 1 || len(state.burnt) != 0 {
+		t.Errorf("reask(); got: %d answers and %d burnt questions, want: 1 and 0", len(state.answers), len(state.burnt))
+	}
+}
+
+func TestReaskUsesSeedOfAnswer(t *testing.T) {
+	useBackend(t, &nativeBKT{})
+	c := DefaultCase()
+	c.Text = nil
+	sq := sqMap["breach"]
+	lines, err := readScript("testdata/breach.script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reask asks the same breach instance again in a session with a seed;
+	// the choices of a breach are shuffled, so the transcript shows which
+	// seed was used.
+	transcript := func(sessionSeed int64, answerSeed *int64) string {
+		model, _ := newStudentModel("bkt")
+		state := newStudentState(&Content{Questions: []Question{c}}, model)
+		state.setSeed(1)
+		inst := sq.newInstance(c.preprocess(), state.rng)
+		state.setSeed(sessionSeed)
+		state.answers = append(state.answers, &answer{question: c, subQuestion: sq, instance: inst.refs, seed: answerSeed})
+		var out bytes.Buffer
+		reask(newScriptedUserInterface(lines, &out), state, []string{"reask", "1"})
+		return out.String()
+	}
+	seed := int64(1)
+	want := transcript(1, nil)
+	if transcript(3, nil) == want {
+		t.Fatal("reask() with seeds 1 and 3 gives the same transcript, pick other seeds")
+	}
+	if got := transcript(3, &seed); got != want {
+		t.Errorf("reask() of an answer with seed 1 in a session with seed 3; got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -3,6 +3,8 @@ package nits
 // This file contains the implementation of the causality in fact
 // sub question.
 
+import "math/rand"
+
 type causeInFactSubQuestion struct{}
 
 func (cif *causeInFactSubQuestion) getTag() string {
@@ -13,15 +15,34 @@ func (cif *causeInFactSubQuestion) getConcepts() []*Concept {
 	return []*Concept{CauseInFact1}
 }
 
+// applies tells if the sub question can be asked about a case: it needs
+// an act and an injury or damage.
+func (cif *causeInFactSubQuestion) applies(pp *preprocessedCase) bool {
+	for e := range pp.events {
+		if _, ok := e.(*Act); ok {
+			return len(pp.injuriesOrDamages) > 0
+		}
+	}
+	return false
+}
+
 var _ = addSubQuestion(&causeInFactSubQuestion{})
 
+// newInstance picks a random act and a random piece of damage in the case.
+func (cif *causeInFactSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	return pp.newInstance(cif, pp.randomAct(r), pp.randomInjuryOrDamage(r))
+}
+
 // ask asks the sub question
-func (cif *causeInFactSubQuestion) ask(c *Case, ui *userInterface, state *studentState) bool {
-	// Finds a random piece of damage and a random act in the case and then
-	// figures out if they are connected by a link of causality.
-  pp := c.preprocess()
-	dam := pp.randomInjuryOrDamage()
-	act := pp.randomAct()
+func (cif *causeInFactSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	// Figures out if the act and the damage are connected by a link of
+	// causality.
+	pp := c.preprocess()
+	act, ok1 := pp.ref(inst, 0).(*Act)
+	dam, ok2 := pp.ref(inst, 1).(InjuryOrDamage)
+	if !ok1 || !ok2 {
+		return badInstance(ui, inst)
+	}
 	rightAnswer := isCauseInFact(dam, act)
 
 	displayQuestion := func([]string) bool {
@@ -38,20 +59,20 @@ func (cif *causeInFactSubQuestion) ask(c *Case, ui *userInterface, state *studen
 	pushSubQuestionCommandContext(ui, displayQuestion)
 	defer ui.popCommandContext()
 
-	attempts := 0
+	responses := make([]string, 0)
 
 	for {
 		answer, ret := ui.yesNo("Your answer")
 		if ret {
 			return ret
 		}
+		responses = append(responses, yesNoText(answer))
 		if answer != rightAnswer {
 			ui.println("Please try again :-(")
-			attempts++
 			continue
 		}
 		ui.println("Correct :-)")
-		state.registerAnswer(c, cif, attempts == 0)
+		state.registerAnswer(c, cif, responses)
 		return false
 	}
 }
@@ -3,7 +3,7 @@ package nits
 import "testing"
 
 func TestIsParentOf(t *testing.T) {
-	pp := preprocess(DefaultCase())
+	pp := DefaultCase().preprocess()
 	plows := pp.findEvent("plows")
 	carDies := pp.findEvent("car_dies")
 	if !isParentOf(carDies, plows) {
@@ -13,3 +13,88 @@ func TestIsParentOf(t *testing.T) {
 		t.Error("isParentOf(plows, carDies); got:true, want:false")
 	}
 }
+
+func TestIsProximateCause(t *testing.T) {
+	pp := DefaultCase().preprocess()
+	plows := pp.findEvent("plows").(*Act)
+	flees := pp.findEvent("car_dies").getConsequences()[0]
+	rookesInjury := plows.getConsequences()[0].getInjuriesOrDamages()[0]
+	if !isProximateCause(rookesInjury, flees) {
+		t.Error("isProximateCause(rookesInjury, flees); got:false, want:true")
+	}
+	plows.Foreseeability = Superseding
+	if isProximateCause(rookesInjury, flees) {
+		t.Error("isProximateCause(rookesInjury, flees) with a superseding cause; got:true, want:false")
+	}
+	if breakers := chainBreakers(rookesInjury, flees); len(breakers) != 1 || breakers[0] != plows {
+		t.Errorf("chainBreakers(rookesInjury, flees); got:%v, want:[plows]", breakers)
+	}
+}
+
+func TestNamesMatch(t *testing.T) {
+	demi := &Person{Name: "Demi"}
+	ashton := &Person{Name: "Ashton"}
+	persons := personSet([]*Person{demi, ashton})
+	if !namesMatch([]string{"demi", "ashton"}, persons) {
+		t.Error("namesMatch(demi, ashton); got:false, want:true")
+	}
+	if namesMatch([]string{"demi"}, persons) {
+		t.Error("namesMatch(demi); got:true, want:false")
+	}
+}
+
+func TestVicariouslyLiable(t *testing.T) {
+	c := DefaultCase()
+	pp := c.preprocess()
+	var demi, mayko *Person
+	for p := range pp.persons {
+		switch p.Name {
+		case "Demi":
+			demi = p
+		case "Mayko":
+			mayko = p
+		}
+	}
+	got := pp.vicariouslyLiable(personSet([]*Person{demi}))
+	if _, ok := got[mayko]; !ok || len(got) != 1 {
+		t.Errorf("vicariouslyLiable(Demi); got: %v, want: Mayko", personNames(got))
+	}
+	if got := pp.vicariouslyLiable(personSet([]*Person{demi, mayko})); len(got) != 0 {
+		t.Errorf("vicariouslyLiable(Demi, Mayko); got: %v, want: nobody", personNames(got))
+	}
+	if got := len(pp.vicariousDamages()); got != 1 {
+		t.Errorf("vicariousDamages(); got: %d, want: 1", got)
+	}
+}
+
+func TestSharedConsequence(t *testing.T) {
+	alice := &Person{Name: "Alice"}
+	bob := &Person{Name: "Bob"}
+	carol := &Person{Name: "Carol"}
+	injury := &BodilyInjury{Description: "Carol is hurt", Persons: []*Person{carol}}
+	crash := &PassiveEvent{ID: "crash", InjuriesOrDamages: []InjuryOrDamage{injury}}
+	duty := &Duty{Description: "Drive carefully", OwedFrom: []*Person{alice}, OwedTo: []*Person{carol}}
+	speeds := &Act{ID: "speeds", Person: alice, Consequences: []Event{crash}, Duty: duty}
+	swerves := &Act{ID: "swerves", Person: bob, Consequences: []Event{crash}}
+	c := &Case{ShortName: "case_shared", RootEvents: []Event{speeds, swerves}}
+	pp := c.preprocess()
+
+	if got := len(crash.getDirectCauses()); got != 2 {
+		t.Errorf("crash.getDirectCauses(); got: %d causes, want: 2", got)
+	}
+	if !isCauseInFact(injury, swerves) {
+		t.Error("isCauseInFact(injury, swerves); got:false, want:true")
+	}
+	if got := len(pp.causeInFactPairs()); got != 2 {
+		t.Errorf("causeInFactPairs(); got: %d pairs, want: 2", got)
+	}
+	if got := len(findDuties(injury)); got != 1 {
+		t.Errorf("findDuties(injury); got: %d duties, want: 1", got)
+	}
+	// Preprocessing again must not record the causes twice.
+	c.preproc = nil
+	c.preprocess()
+	if got := len(crash.getDirectCauses()); got != 2 {
+		t.Errorf("crash.getDirectCauses() after preprocessing twice; got: %d causes, want: 2", got)
+	}
+}
@@ -0,0 +1,94 @@
+package nits
+
+// This file implements the claim sub question. It presents a claim that a
+// person in the case makes and asks if it is a valid defence. The claims in
+// a case are irrelevant, and their explanation says why.
+
+import (
+	"math/rand"
+	"sort"
+)
+
+type claimSubQuestion struct{}
+
+func (cl *claimSubQuestion) getTag() string {
+	return "claim"
+}
+
+func (cl *claimSubQuestion) getConcepts() []*Concept {
+	return []*Concept{IrrelevantDefence1}
+}
+
+func (cl *claimSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.claims) > 0
+}
+
+var _ = addSubQuestion(&claimSubQuestion{})
+
+// randomClaim finds a random claim in a case, or nil if there are none.
+func (p *preprocessedCase) randomClaim(r *rand.Rand) *Claim {
+	claims := make([]*Claim, 0, len(p.claims))
+	for c := range p.claims {
+		claims = append(claims, c)
+	}
+	if len(claims) == 0 {
+		return nil
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		return p.key(claims[i]) < p.key(claims[j])
+	})
+	return claims[r.Intn(len(claims))]
+}
+
+// newInstance picks a random claim.
+func (cl *claimSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	claim := pp.randomClaim(r)
+	if claim == nil {
+		return nil
+	}
+	return pp.newInstance(cl, claim)
+}
+
+// ask asks the claim sub question.
+func (cl *claimSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	claim, ok := c.preprocess().ref(inst, 0).(*Claim)
+	if !ok {
+		return badInstance(ui, inst)
+	}
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("In this case, %s makes the following claim:", claim.Person.Name)
+		ui.println(claim.Description)
+		if claim.event != nil {
+			ui.println("about this event:")
+			ui.println(claim.event.getDescription())
+		}
+		ui.println("Is this a valid defence?")
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses := make([]string, 0)
+
+	for {
+		answer, ret := ui.yesNo("Your answer")
+		if ret {
+			return ret
+		}
+		responses = append(responses, yesNoText(answer))
+		if answer {
+			ui.println("Please try again :-(")
+			continue
+		}
+		ui.println("Correct :-) This claim is irrelevant.")
+		ui.newline()
+		ui.explain(claim.Explanation)
+		state.registerAnswer(c, cl, responses)
+		return false
+	}
+}
@@ -3,6 +3,7 @@ package nits
 // This file creates all the concepts in NITS (as global variables).
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 )
@@ -15,6 +16,7 @@ type Concept struct {
 	explanation *Explanation
 	related     []*Concept
 	hints       []string
+	bkt         *conceptBKT // BKT parameters for this concept.
 }
 
 // A global list of all concepts in the system.
@@ -34,6 +36,70 @@ func (c *Concept) sortRelatedConcepts() {
 	})
 }
 
+// conceptBKT are the BKT parameters that a concept declares. Parameters
+// that are nil are not declared and use the global defaults, so that a
+// declared zero really is zero.
+type conceptBKT struct {
+	pInit  *float64
+	pLearn *float64
+	pSlip  *float64
+	pGuess *float64
+}
+
+// prob returns a pointer to a probability, for declaring BKT parameters.
+func prob(p float64) *float64 {
+	return &p
+}
+
+// check checks that the declared parameters are probabilities. The
+// probabilities of knowing a concept at the start, of a slip and of a guess
+// can not be 0 or 1 either: the knowledge trace would divide 0 by 0 on an
+// answer that such a parameter rules out.
+func (b *conceptBKT) check() error {
+	for _, p := range []struct {
+		name   string
+		value  *float64
+		strict bool
+	}{
+		{"pInit", b.pInit, true},
+		{"pLearn", b.pLearn, false},
+		{"pSlip", b.pSlip, true},
+		{"pGuess", b.pGuess, true},
+	} {
+		switch {
+		case p.value == nil:
+		case p.strict && (*p.value <= 0 || *p.value >= 1):
+			return errors.New(fmt.Sprintf("%s %v is not between 0 and 1", p.name, *p.value))
+		case *p.value < 0 || *p.value > 1:
+			return errors.New(fmt.Sprintf("%s %v is not a probability", p.name, *p.value))
+		}
+	}
+	return nil
+}
+
+// getBKTParams returns the effective BKT parameters for a concept: the
+// parameters declared on the concept, with the global defaults for the
+// ones that are not declared.
+func (c *Concept) getBKTParams() bktParams {
+	p := defaultBKTParams
+	if c.bkt == nil {
+		return p
+	}
+	if c.bkt.pInit != nil {
+		p.pInit = *c.bkt.pInit
+	}
+	if c.bkt.pLearn != nil {
+		p.pLearn = *c.bkt.pLearn
+	}
+	if c.bkt.pSlip != nil {
+		p.pSlip = *c.bkt.pSlip
+	}
+	if c.bkt.pGuess != nil {
+		p.pGuess = *c.bkt.pGuess
+	}
+	return p
+}
+
 // GetReferenceText gets a descriptive text for a concept. This allows a
 // concept to be used as reference.
 func (c *Concept) GetReferenceText() string {
@@ -46,6 +112,8 @@ var (
 		name:      "defendant",
 		shortName: "defendant0",
 		level:     0,
+		// Asked by having the student enter names, which is hard to guess.
+		bkt: &conceptBKT{pInit: prob(0.3), pGuess: prob(0.1)},
 		explanation: &Explanation{
 			Text: []string{
 				"Defendants are the people that are being sued. Typically these are the people " +
@@ -57,6 +125,7 @@ var (
 		name:      "plaintiff",
 		shortName: "plaintiff0",
 		level:     0,
+		bkt:       &conceptBKT{pInit: prob(0.3)},
 		related:   []*Concept{Defendant0},
 		explanation: &Explanation{
 			Text: []string{
@@ -66,10 +135,35 @@ var (
 			},
 		},
 	}).add()
+	InjuryOrDamage0 = (&Concept{
+		name:      "injury or damage",
+		shortName: "injuryordamage0",
+		level:     0,
+		explanation: &Explanation{
+			Text: []string{
+				"There is no negligence without harm: the plaintiff must have suffered a bodily injury or " +
+					"damage to their property.",
+			},
+		},
+	}).add()
 	Foreseeability1 = (&Concept{
 		name:      "foreseeability (basic)",
 		shortName: "foreseeability1",
 		level:     1,
+		explanation: &Explanation{
+			Text: []string{
+				"An act is a proximate cause of a damage if the damage was a foreseeable consequence of " +
+					"the act. Cause-in-fact alone is not enough: the defendant is only liable for the kinds " +
+					"of harm that a reasonable person could have foreseen.",
+				"An intervening cause is an event that happens after the defendant's act and contributes " +
+					"to the damage. If the intervening cause was itself foreseeable it does not break the " +
+					"chain of causation. If it was not, it is a superseding cause and the defendant's act " +
+					"is no longer a proximate cause of the damage.",
+			},
+			References: []Reference{
+				&Restatement{"440"},
+			},
+		},
 	}).add()
 	CauseInFact1 = (&Concept{
 		name:      "cause in fact (basic)",
@@ -135,21 +229,62 @@ var (
 		shortName: "contribneg1",
 		level:     1,
 		related:   []*Concept{ComparativeNegligence1, ModifiedComparativeNegligence1, PureComparativeNegligence1},
+		explanation: &Explanation{Text: []string{
+			"Under the doctrine of contributory negligence a plaintiff who was negligent themselves, and " +
+				"whose negligence contributed to the injury or damage, can not recover anything. Only a few " +
+				"jurisdictions still follow this doctrine; most have replaced it with some form of " +
+				"comparative negligence.",
+		}},
 	}).add()
 	PreponderanceOfTheElements1 = (&Concept{
 		name:      "preponderance of the elements",
 		shortName: "prepond1",
 		level:     1,
 	}).add()
+	IrrelevantDefence1 = (&Concept{
+		name:      "irrelevant defences",
+		shortName: "irreldef1",
+		level:     1,
+		explanation: &Explanation{
+			Text: []string{
+				"Defendants often claim things that have no bearing on their liability. A claim is only a " +
+					"defence if it takes away one of the elements of the prima facie case, or if it is a " +
+					"recognised defence like contributory negligence or assumption of risk.",
+			},
+		},
+	}).add()
 	AssumptionOfRisk1 = (&Concept{
 		name:      "assumption of risk",
 		shortName: "assumprisk1",
 		level:     1,
+		explanation: &Explanation{
+			Text: []string{
+				"A plaintiff who knew of a risk and voluntarily exposed themselves to it can not recover " +
+					"for the harm that results from it. The defendant can raise this as a defence.",
+			},
+			References: []Reference{
+				&Restatement{"496A"},
+			},
+		},
+	}).add()
+	StatuteOfLimitations1 = (&Concept{
+		name:      "statute of limitations",
+		shortName: "statlim1",
+		level:     1,
+		explanation: &Explanation{
+			Text: []string{
+				"A statute of limitations sets the time within which a plaintiff has to file a lawsuit. " +
+					"A defendant who is sued after that time can raise the statute of limitations as a " +
+					"defence, no matter how strong the plaintiff's case is.",
+			},
+		},
 	}).add()
 	NegligencePerSe1 = (&Concept{
 		name:      "negligence per se",
 		shortName: "negperse1",
 		level:     1,
+		// Asked as an open question.
+		bkt: &conceptBKT{pGuess: prob(0.1)},
 		explanation: &Explanation{
 			Text: []string{
 				"In order for there to be negligence per se, the defendant must have been acting in violation of a " +
@@ -228,10 +363,40 @@ var (
 			},
 		},
 	}).add()
+	Duty1 = (&Concept{
+		name:      "duty of care",
+		shortName: "duty1",
+		level:     1,
+		// Asked by having the student enter names, which is hard to guess.
+		bkt: &conceptBKT{pGuess: prob(0.1)},
+		explanation: &Explanation{
+			Text: []string{
+				"A duty of care is a legal obligation to act with reasonable care towards others. A duty " +
+					"is owed by one or more people (the ones who have to take care) to one or more other " +
+					"people (the ones who could be harmed if they do not).",
+				"There can only be negligence if the defendant owed a duty to the plaintiff.",
+			},
+		},
+	}).add()
+	Breach1 = (&Concept{
+		name:      "breach of duty",
+		shortName: "breach1",
+		level:     1,
+		related:   []*Concept{Duty1},
+		explanation: &Explanation{
+			Text: []string{
+				"A duty is breached when the person who owes it does not meet the standard of care, that " +
+					"is: does not act like a reasonable person would have acted in the same circumstances. " +
+					"The breach is the act (or the failure to act) that fell short of that standard.",
+			},
+		},
+	}).add()
 	primaFacie2 = (&Concept{
 		name:      "prima facie case",
 		shortName: "primafacie",
 		level:     2,
+		related:   []*Concept{InjuryOrDamage0, Duty1, Breach1, CauseInFact1},
+		bkt:       &conceptBKT{pInit: prob(0.05), pLearn: prob(0.1)},
 		explanation: &Explanation{
 			Text: []string{
 				"We say there is a prima facie case to answer if the case contains all of the following four" +
@@ -246,9 +411,12 @@ var (
 	}).add()
 )
 
-func initConcepts() {
-	// These are here to break type checking loops.
+func init() {
+	// These are here to break type checking loops. They are set in init
+	// rather than in initConcepts, so that they can be changed by content
+	// files.
 	Defendant0.related = []*Concept{Plaintiff0}
+	Duty1.related = []*Concept{Breach1}
 	ComparativeNegligence1.related = []*Concept{
 		ModifiedComparativeNegligence1,
 		PureComparativeNegligence1,
@@ -264,7 +432,10 @@ func initConcepts() {
 		ModifiedComparativeNegligence1,
 		ContributoryNegligence1,
 	}
+}
 
+// initConcepts checks the concepts and gets them ready for use.
+func initConcepts() {
 	m := make(map[string]interface{})
 
 	for _, c := range allConcepts {
@@ -0,0 +1,1495 @@
+package nits
+
+// This file implements loading NITS content from YAML or JSON files, so
+// that authors can add questions, cases and concepts without a Go
+// toolchain, and exporting the compiled-in content to these files.
+//
+// A content directory contains any number of .yaml, .yml and .json files.
+// Each file can declare concepts and questions. Concepts are referenced by
+// their short name. Within a case every object (person, event, duty, ...)
+// has an id, by which the other objects in the case refer to it.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var contentFlag = flag.String("content", "", "Directory with YAML/JSON content to use instead of the built-in content")
+
+// conceptSources records the content file that each concept was loaded
+// from. Concepts that are not in the map are built-in.
+var conceptSources = make(map[*Concept]string)
+
+// contentFile is the content of a single content file.
+type contentFile struct {
+	Concepts  []*conceptSpec  `json:"concepts,omitempty" yaml:"concepts,omitempty"`
+	Questions []*questionSpec `json:"questions,omitempty" yaml:"questions,omitempty"`
+}
+
+// conceptSpec describes a concept. A concept with the short name of a
+// built-in concept replaces the description of that concept.
+type conceptSpec struct {
+	ShortName   string           `json:"shortName" yaml:"shortName"`
+	Name        string           `json:"name" yaml:"name"`
+	Level       int              `json:"level" yaml:"level"`
+	Related     []string         `json:"related,omitempty" yaml:"related,omitempty"`
+	Hints       []string         `json:"hints,omitempty" yaml:"hints,omitempty"`
+	Explanation *explanationSpec `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+	BKT         *bktSpec         `json:"bkt,omitempty" yaml:"bkt,omitempty"`
+}
+
+// bktSpec describes the BKT parameters of a concept. Missing parameters
+// use the defaults.
+type bktSpec struct {
+	PInit  *float64 `json:"pInit,omitempty" yaml:"pInit,omitempty"`
+	PLearn *float64 `json:"pLearn,omitempty" yaml:"pLearn,omitempty"`
+	PSlip  *float64 `json:"pSlip,omitempty" yaml:"pSlip,omitempty"`
+	PGuess *float64 `json:"pGuess,omitempty" yaml:"pGuess,omitempty"`
+}
+
+type explanationSpec struct {
+	Text       []string         `json:"text,omitempty" yaml:"text,omitempty"`
+	References []*referenceSpec `json:"references,omitempty" yaml:"references,omitempty"`
+}
+
+// referenceSpec describes a reference. Exactly one of the fields is set.
+type referenceSpec struct {
+	Restatement string `json:"restatement,omitempty" yaml:"restatement,omitempty"`
+	URL         string `json:"url,omitempty" yaml:"url,omitempty"`
+	Concept     string `json:"concept,omitempty" yaml:"concept,omitempty"`
+}
+
+// The types of questions in a content file.
+const (
+	multipleChoiceType = "multipleChoice"
+	propsType          = "props"
+	caseType           = "case"
+)
+
+// questionSpec describes a question. Which fields are used depends on the
+// type of the question.
+type questionSpec struct {
+	Type      string `json:"type" yaml:"type"`
+	ShortName string `json:"shortName" yaml:"shortName"`
+
+	// Multiple choice questions.
+	Question []string      `json:"question,omitempty" yaml:"question,omitempty"`
+	Concepts []string      `json:"concepts,omitempty" yaml:"concepts,omitempty"`
+	Answers  []*answerSpec `json:"answers,omitempty" yaml:"answers,omitempty"`
+
+	// Proposition questions.
+	Propositions []*propositionSpec `json:"propositions,omitempty" yaml:"propositions,omitempty"`
+
+	// Cases.
+	Case *caseSpec `json:"case,omitempty" yaml:"case,omitempty"`
+}
+
+type answerSpec struct {
+	Text           string           `json:"text" yaml:"text"`
+	Concepts       []string         `json:"concepts,omitempty" yaml:"concepts,omitempty"`
+	Explanation    *explanationSpec `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+	Correct        bool             `json:"correct,omitempty" yaml:"correct,omitempty"`
+	NoneOfTheAbove bool             `json:"noneOfTheAbove,omitempty" yaml:"noneOfTheAbove,omitempty"`
+}
+
+type propositionSpec struct {
+	Proposition string   `json:"proposition" yaml:"proposition"`
+	Concepts    []string `json:"concepts,omitempty" yaml:"concepts,omitempty"`
+	True        bool     `json:"true,omitempty" yaml:"true,omitempty"`
+}
+
+// caseSpec describes the graph of a case.
+type caseSpec struct {
+	Text                    []string                `json:"text" yaml:"text"`
+	RootEvents              []string                `json:"rootEvents" yaml:"rootEvents"`
+	Persons                 []*personSpec           `json:"persons,omitempty" yaml:"persons,omitempty"`
+	Events                  []*eventSpec            `json:"events,omitempty" yaml:"events,omitempty"`
+	Duties                  []*dutySpec             `json:"duties,omitempty" yaml:"duties,omitempty"`
+	InjuriesOrDamages       []*damageSpec           `json:"injuriesOrDamages,omitempty" yaml:"injuriesOrDamages,omitempty"`
+	BrokenLegalRequirements []*legalRequirementSpec `json:"brokenLegalRequirements,omitempty" yaml:"brokenLegalRequirements,omitempty"`
+	Claims                  []*claimSpec            `json:"claims,omitempty" yaml:"claims,omitempty"`
+	Defences                []*defenceSpec          `json:"defences,omitempty" yaml:"defences,omitempty"`
+	Relationships           []*relationshipSpec     `json:"relationships,omitempty" yaml:"relationships,omitempty"`
+	Jurisdiction            *jurisdictionSpec       `json:"jurisdiction,omitempty" yaml:"jurisdiction,omitempty"`
+	SubQuestions            []string                `json:"subQuestions,omitempty" yaml:"subQuestions,omitempty"`
+	ExcludedSubQuestions    []string                `json:"excludedSubQuestions,omitempty" yaml:"excludedSubQuestions,omitempty"`
+}
+
+type defenceSpec struct {
+	ID          string           `json:"id" yaml:"id"`
+	Kind        string           `json:"kind" yaml:"kind"`
+	Description string           `json:"description" yaml:"description"`
+	RaisedBy    []string         `json:"raisedBy" yaml:"raisedBy"`
+	Against     []string         `json:"against" yaml:"against"`
+	Explanation *explanationSpec `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+}
+
+// defenceKindNames are the names of the kinds of defences in content files.
+var defenceKindNames = map[DefenceKind]string{
+	AssumptionOfRisk:              "assumptionOfRisk",
+	ContributoryNegligenceDefence: "contributoryNegligence",
+	StatuteOfLimitations:          "statuteOfLimitations",
+}
+
+// parseDefenceKind parses the name of a kind of defence.
+func parseDefenceKind(name string) (DefenceKind, error) {
+	for k, n := range defenceKindNames {
+		if n == name {
+			return k, nil
+		}
+	}
+	return AssumptionOfRisk, errors.New(fmt.Sprintf("unknown kind of defence %q", name))
+}
+
+type relationshipSpec struct {
+	Kind        string `json:"kind" yaml:"kind"`
+	Superior    string `json:"superior" yaml:"superior"`
+	Subordinate string `json:"subordinate" yaml:"subordinate"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// relationshipKindNames are the names of the kinds of relationships in
+// content files.
+var relationshipKindNames = map[RelationshipKind]string{
+	Employment: "employment",
+	Agency:     "agency",
+	Ownership:  "ownership",
+}
+
+// parseRelationshipKind parses the name of a kind of relationship.
+func parseRelationshipKind(name string) (RelationshipKind, error) {
+	for k, n := range relationshipKindNames {
+		if n == name {
+			return k, nil
+		}
+	}
+	return Employment, errors.New(fmt.Sprintf("unknown kind of relationship %q", name))
+}
+
+type jurisdictionSpec struct {
+	Name                          string `json:"name" yaml:"name"`
+	Rule                          string `json:"rule" yaml:"rule"`
+	AbrogatedCollateralSourceRule bool   `json:"abrogatedCollateralSourceRule,omitempty" yaml:"abrogatedCollateralSourceRule,omitempty"`
+}
+
+// negligenceRuleNames are the names of the negligence rules in content
+// files.
+var negligenceRuleNames = map[NegligenceRule]string{
+	PureComparative:       "pureComparative",
+	ModifiedComparative50: "modifiedComparative50",
+	ModifiedComparative51: "modifiedComparative51",
+	Contributory:          "contributory",
+}
+
+// parseNegligenceRule parses the name of a negligence rule.
+func parseNegligenceRule(name string) (NegligenceRule, error) {
+	for r, n := range negligenceRuleNames {
+		if n == name {
+			return r, nil
+		}
+	}
+	return NoNegligenceRule, errors.New(fmt.Sprintf("unknown negligence rule %q", name))
+}
+
+type personSpec struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// The types of events in a case.
+const (
+	actType     = "act"
+	passiveType = "passive"
+)
+
+type eventSpec struct {
+	ID                string       `json:"id" yaml:"id"`
+	Type              string       `json:"type" yaml:"type"`
+	Person            string       `json:"person,omitempty" yaml:"person,omitempty"` // Acts only.
+	Description       string       `json:"description" yaml:"description"`
+	Consequences      []string     `json:"consequences,omitempty" yaml:"consequences,omitempty"`
+	Duty              string       `json:"duty,omitempty" yaml:"duty,omitempty"`
+	NegPerSe          string       `json:"negPerSe,omitempty" yaml:"negPerSe,omitempty"`
+	InjuriesOrDamages []string     `json:"injuriesOrDamages,omitempty" yaml:"injuriesOrDamages,omitempty"`
+	Claims            []string     `json:"claims,omitempty" yaml:"claims,omitempty"`
+	Defences          []string     `json:"defences,omitempty" yaml:"defences,omitempty"`
+	Foreseeability    string       `json:"foreseeability,omitempty" yaml:"foreseeability,omitempty"`
+	ResIpsa           *resIpsaSpec `json:"resIpsa,omitempty" yaml:"resIpsa,omitempty"`
+}
+
+// foreseeabilityNames are the names of the foreseeability of events in
+// content files. Events are foreseeable unless marked otherwise.
+var foreseeabilityNames = map[Foreseeability]string{
+	Foreseeable:   "foreseeable",
+	Unforeseeable: "unforeseeable",
+	Intervening:   "intervening",
+	Superseding:   "superseding",
+}
+
+// parseForeseeability parses the name of a foreseeability.
+func parseForeseeability(name string) (Foreseeability, error) {
+	if name == "" {
+		return Foreseeable, nil
+	}
+	for f, n := range foreseeabilityNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return Foreseeable, errors.New(fmt.Sprintf("unknown foreseeability %q", name))
+}
+
+type resIpsaSpec struct {
+	UnknownMechanism    bool   `json:"unknownMechanism,omitempty" yaml:"unknownMechanism,omitempty"`
+	OrdinarilyNegligent bool   `json:"ordinarilyNegligent,omitempty" yaml:"ordinarilyNegligent,omitempty"`
+	ExclusiveControl    string `json:"exclusiveControl,omitempty" yaml:"exclusiveControl,omitempty"`
+}
+
+type dutySpec struct {
+	ID          string   `json:"id" yaml:"id"`
+	Description string   `json:"description" yaml:"description"`
+	OwedFrom    []string `json:"owedFrom" yaml:"owedFrom"`
+	OwedTo      []string `json:"owedTo" yaml:"owedTo"`
+}
+
+// The types of injuries or damages in a case.
+const (
+	bodilyInjuryType   = "bodilyInjury"
+	propertyDamageType = "propertyDamage"
+)
+
+type damageSpec struct {
+	ID          string            `json:"id" yaml:"id"`
+	Type        string            `json:"type" yaml:"type"`
+	Description string            `json:"description" yaml:"description"`
+	Persons     []string          `json:"persons" yaml:"persons"`
+	Amounts     []*amountSpec     `json:"amounts,omitempty" yaml:"amounts,omitempty"`
+	Fault       []*faultShareSpec `json:"fault,omitempty" yaml:"fault,omitempty"`
+}
+
+type amountSpec struct {
+	Category    string `json:"category" yaml:"category"`
+	Description string `json:"description" yaml:"description"`
+	Dollars     int    `json:"dollars" yaml:"dollars"`
+}
+
+// damageCategoryNames are the names of the categories of damages in
+// content files.
+var damageCategoryNames = map[DamageCategory]string{
+	Economic:         "economic",
+	NonEconomic:      "nonEconomic",
+	Punitive:         "punitive",
+	CollateralSource: "collateralSource",
+}
+
+// parseDamageCategory parses the name of a category of damages.
+func parseDamageCategory(name string) (DamageCategory, error) {
+	for c, n := range damageCategoryNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return Economic, errors.New(fmt.Sprintf("unknown category of damages %q", name))
+}
+
+type faultShareSpec struct {
+	Person     string `json:"person" yaml:"person"`
+	Percentage int    `json:"percentage" yaml:"percentage"`
+}
+
+type legalRequirementSpec struct {
+	ID           string           `json:"id" yaml:"id"`
+	Description  string           `json:"description" yaml:"description"`
+	Persons      []string         `json:"persons" yaml:"persons"`
+	Consequences []string         `json:"consequences,omitempty" yaml:"consequences,omitempty"`
+	Explanation  *explanationSpec `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+	// The elements of negligence per se.
+	ProtectedClass   string   `json:"protectedClass,omitempty" yaml:"protectedClass,omitempty"`
+	ProtectedPersons []string `json:"protectedPersons,omitempty" yaml:"protectedPersons,omitempty"`
+	HarmTypes        []string `json:"harmTypes,omitempty" yaml:"harmTypes,omitempty"`
+}
+
+// harmTypeNames are the names of the types of harm in content files.
+var harmTypeNames = map[HarmType]string{
+	BodilyHarm:   "bodily",
+	PropertyHarm: "property",
+}
+
+// parseHarmType parses the name of a type of harm.
+func parseHarmType(name string) (HarmType, error) {
+	for h, n := range harmTypeNames {
+		if n == name {
+			return h, nil
+		}
+	}
+	return BodilyHarm, errors.New(fmt.Sprintf("unknown type of harm %q", name))
+}
+
+type claimSpec struct {
+	ID          string           `json:"id" yaml:"id"`
+	Person      string           `json:"person" yaml:"person"`
+	Description string           `json:"description" yaml:"description"`
+	Explanation *explanationSpec `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+}
+
+// --------------------------------------------------------------------
+
+// SelectContent returns the content that NITS operates on: the content in
+// the directory given with --content, or else the built-in content.
+func SelectContent(builtin func() *Content) (*Content, error) {
+	if *contentFlag == "" {
+		return builtin(), nil
+	}
+	return LoadContent(*contentFlag)
+}
+
+// readContentFile reads and parses a single content file. Unknown fields
+// are errors, because they are most likely typos.
+func readContentFile(fname string) (*contentFile, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	cf := &contentFile{}
+	if path.Ext(fname) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cf)
+	} else {
+		err = yaml.UnmarshalStrict(data, cf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// contentFiles returns the content files in a directory, sorted by name.
+func contentFiles(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, fi := range files {
+		switch path.Ext(fi.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !fi.IsDir() {
+				names = append(names, path.Join(dir, fi.Name()))
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LoadContent loads the content from all content files in a directory.
+// The concepts in all files are loaded before the questions, so that a
+// question can use a concept from any file.
+func LoadContent(dir string) (*Content, error) {
+	content, _, err := loadContent(dir, false)
+	return content, err
+}
+
+// loadContent loads the content from all content files in a directory. A
+// lenient load goes on past the problems it finds, leaving out what has
+// them, and returns them as issues tied to the file they are in; otherwise
+// loading stops at the first problem.
+func loadContent(dir string, lenient bool) (*Content, []*lintIssue, error) {
+	fnames, err := contentFiles(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(fnames) == 0 {
+		return nil, nil, errors.New(fmt.Sprintf("no content files in %s", dir))
+	}
+	issues := make([]*lintIssue, 0)
+	// problem handles a problem at a location in the content.
+	problem := func(location string, err error) error {
+		if !lenient {
+			return errors.New(fmt.Sprintf("%s: %v", location, err))
+		}
+		issues = append(issues, &lintIssue{location: location, message: err.Error()})
+		return nil
+	}
+
+	files := make([]*contentFile, 0, len(fnames))
+	names := make([]string, 0, len(fnames))
+	for _, fname := range fnames {
+		cf, err := readContentFile(fname)
+		if err != nil {
+			if err := problem(fname, err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		files = append(files, cf)
+		names = append(names, fname)
+	}
+	fnames = names
+
+	// First creates (or finds) all the concepts, then fills them in, so
+	// that they can refer to each other.
+	declared := make(map[string]string)
+	for i, cf := range files {
+		for _, spec := range cf.Concepts {
+			if spec.ShortName == "" {
+				if err := problem(fnames[i], errors.New("concept without a short name")); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			if other, ok := declared[spec.ShortName]; ok {
+				if err := problem(fnames[i], errors.New(fmt.Sprintf("concept %s already declared in %s", spec.ShortName, other))); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			declared[spec.ShortName] = fnames[i]
+			c := lookupConcept(spec.ShortName)
+			if c == nil {
+				c = (&Concept{shortName: spec.ShortName}).add()
+			}
+			conceptSources[c] = fnames[i]
+		}
+	}
+	for i, cf := range files {
+		for _, spec := range cf.Concepts {
+			if declared[spec.ShortName] != fnames[i] {
+				continue
+			}
+			if err := spec.apply(lookupConcept(spec.ShortName)); err != nil {
+				if err := problem(fmt.Sprintf("%s: concept %s", fnames[i], spec.ShortName), err); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	content := &Content{Questions: make([]Question, 0), sources: make(map[string]string)}
+	for i, cf := range files {
+		for _, spec := range cf.Questions {
+			location := fmt.Sprintf("%s: question %s", fnames[i], spec.ShortName)
+			q, problems, err := spec.build(lenient)
+			if err != nil {
+				if err := problem(location, err); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			for _, err := range problems {
+				problem(location, err)
+			}
+			content.Questions = append(content.Questions, q)
+			content.sources[spec.ShortName] = fnames[i]
+		}
+	}
+	return content, issues, nil
+}
+
+// lookupConcept finds a concept by its short name.
+func lookupConcept(shortName string) *Concept {
+	for _, c := range allConcepts {
+		if c.shortName == shortName {
+			return c
+		}
+	}
+	return nil
+}
+
+// lookupConcepts finds a list of concepts by their short names.
+func lookupConcepts(shortNames []string) ([]*Concept, error) {
+	if shortNames == nil {
+		return nil, nil
+	}
+	concepts := make([]*Concept, 0, len(shortNames))
+	for _, name := range shortNames {
+		c := lookupConcept(name)
+		if c == nil {
+			return nil, errors.New(fmt.Sprintf("unknown concept %s", name))
+		}
+		concepts = append(concepts, c)
+	}
+	return concepts, nil
+}
+
+// apply sets the fields of a concept from its description.
+func (spec *conceptSpec) apply(c *Concept) error {
+	related, err := lookupConcepts(spec.Related)
+	if err != nil {
+		return err
+	}
+	explanation, err := spec.Explanation.build()
+	if err != nil {
+		return err
+	}
+	var bkt *conceptBKT
+	if spec.BKT != nil {
+		bkt = &conceptBKT{pInit: spec.BKT.PInit, pLearn: spec.BKT.PLearn, pSlip: spec.BKT.PSlip, pGuess: spec.BKT.PGuess}
+		if err := bkt.check(); err != nil {
+			return err
+		}
+	}
+	c.name = spec.Name
+	if c.name == "" {
+		c.name = spec.ShortName
+	}
+	c.level = spec.Level
+	c.related = related
+	c.hints = spec.Hints
+	c.explanation = explanation
+	c.bkt = bkt
+	return nil
+}
+
+// build builds an explanation from its description. A nil description
+// makes a nil explanation.
+func (spec *explanationSpec) build() (*Explanation, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	e := &Explanation{Text: spec.Text}
+	for _, r := range spec.References {
+		switch {
+		case r.Restatement != "":
+			e.References = append(e.References, &Restatement{Paragraph: r.Restatement})
+		case r.URL != "":
+			e.References = append(e.References, &URL{Url: r.URL})
+		case r.Concept != "":
+			c := lookupConcept(r.Concept)
+			if c == nil {
+				return nil, errors.New(fmt.Sprintf("unknown concept %s in reference", r.Concept))
+			}
+			e.References = append(e.References, c)
+		default:
+			return nil, errors.New("empty reference")
+		}
+	}
+	return e, nil
+}
+
+// build builds a question from its description. A lenient build of a case
+// also returns the problems it went past, see caseSpec.build.
+func (spec *questionSpec) build(lenient bool) (Question, []error, error) {
+	if spec.ShortName == "" {
+		return nil, nil, errors.New("question without a short name")
+	}
+	switch spec.Type {
+	case multipleChoiceType:
+		concepts, err := lookupConcepts(spec.Concepts)
+		if err != nil {
+			return nil, nil, err
+		}
+		q := &MultipleChoiceQuestion{ShortName: spec.ShortName, Question: spec.Question, Concepts: concepts}
+		for _, as := range spec.Answers {
+			a := &Answer{Text: as.Text, Correct: as.Correct, NoneOfTheAbove: as.NoneOfTheAbove}
+			if a.Concepts, err = lookupConcepts(as.Concepts); err != nil {
+				return nil, nil, err
+			}
+			e, err := as.Explanation.build()
+			if err != nil {
+				return nil, nil, err
+			}
+			if e != nil {
+				a.Explanation = *e
+			}
+			q.Answers = append(q.Answers, a)
+		}
+		return q, nil, nil
+	case propsType:
+		q := &PropsQuestion{ShortName: spec.ShortName}
+		for _, ps := range spec.Propositions {
+			concepts, err := lookupConcepts(ps.Concepts)
+			if err != nil {
+				return nil, nil, err
+			}
+			q.Propositions = append(q.Propositions, &Proposition{Proposition: ps.Proposition, Concepts: concepts, True: ps.True})
+		}
+		return q, nil, nil
+	case caseType:
+		if spec.Case == nil {
+			return nil, nil, errors.New("case question without a case")
+		}
+		c, problems, err := spec.Case.build(spec.ShortName, lenient)
+		if err != nil {
+			return nil, nil, err
+		}
+		return c, problems, nil
+	}
+	return nil, nil, errors.New(fmt.Sprintf("unknown question type %q", spec.Type))
+}
+
+// caseBuilder keeps track of the objects in a case while it is being
+// built, by id. A lenient builder goes on past the problems it finds,
+// leaving out the objects and references that have them, and keeps the
+// problems so that they can all be reported.
+type caseBuilder struct {
+	ids      map[string]string // Kind of object per id.
+	persons  map[string]*Person
+	events   map[string]Event
+	duties   map[string]*Duty
+	damages  map[string]InjuryOrDamage
+	negPerSe map[string]*BrokenLegalRequirement
+	claims   map[string]*Claim
+	defences map[string]*Defence
+	lenient  bool
+	problems []error
+}
+
+// check handles a problem with an object of a case. A lenient builder
+// keeps the problem and returns nil, so that the build can go on;
+// otherwise the problem is returned as an error.
+func (b *caseBuilder) check(what string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if what != "" {
+		err = errors.New(fmt.Sprintf("%s: %v", what, err))
+	}
+	if !b.lenient {
+		return err
+	}
+	b.problems = append(b.problems, err)
+	return nil
+}
+
+// declare registers an id. Ids must be unique within a case.
+func (b *caseBuilder) declare(kind, id string) error {
+	if id == "" {
+		return errors.New(fmt.Sprintf("%s without an id", kind))
+	}
+	if other, ok := b.ids[id]; ok {
+		return errors.New(fmt.Sprintf("duplicate id %s (%s and %s)", id, other, kind))
+	}
+	b.ids[id] = kind
+	return nil
+}
+
+func (b *caseBuilder) person(id string) (*Person, error) {
+	if p, ok := b.persons[id]; ok {
+		return p, nil
+	}
+	return nil, errors.New(fmt.Sprintf("unknown person %s", id))
+}
+
+// personList finds a list of persons. The persons that are not known are
+// left out of the list, and the first of them is returned as an error.
+func (b *caseBuilder) personList(ids []string) ([]*Person, error) {
+	persons := make([]*Person, 0, len(ids))
+	var first error
+	for _, id := range ids {
+		p, err := b.person(id)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		persons = append(persons, p)
+	}
+	return persons, first
+}
+
+// eventList finds a list of events, like personList.
+func (b *caseBuilder) eventList(ids []string) ([]Event, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	events := make([]Event, 0, len(ids))
+	var first error
+	for _, id := range ids {
+		e, ok := b.events[id]
+		if !ok {
+			if first == nil {
+				first = errors.New(fmt.Sprintf("unknown event %s", id))
+			}
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, first
+}
+
+// subQuestionTags checks the tags of the sub questions that a case limits
+// or excludes. Tags that are not known are left out by a lenient builder.
+func (b *caseBuilder) subQuestionTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return tags, nil
+	}
+	known := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if _, ok := sqMap[tag]; !ok {
+			if err := b.check("", errors.New(fmt.Sprintf("unknown sub question %s", tag))); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		known = append(known, tag)
+	}
+	return known, nil
+}
+
+// build builds a case from its description. It first creates all the
+// objects and then links them together. A lenient build returns the case
+// together with the problems it went past; it also returns a case with
+// structural problems, which the linter reports itself.
+func (spec *caseSpec) build(shortName string, lenient bool) (*Case, []error, error) {
+	b := &caseBuilder{
+		ids:      make(map[string]string),
+		persons:  make(map[string]*Person),
+		events:   make(map[string]Event),
+		duties:   make(map[string]*Duty),
+		damages:  make(map[string]InjuryOrDamage),
+		negPerSe: make(map[string]*BrokenLegalRequirement),
+		claims:   make(map[string]*Claim),
+		defences: make(map[string]*Defence),
+		lenient:  lenient,
+	}
+
+	for _, ps := range spec.Persons {
+		if err := b.declare("person", ps.ID); err != nil {
+			if err := b.check("", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		b.persons[ps.ID] = &Person{ID: ps.ID, Name: ps.Name}
+	}
+	for _, ds := range spec.Duties {
+		if err := b.declare("duty", ds.ID); err != nil {
+			if err := b.check("", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		what := fmt.Sprintf("duty %s", ds.ID)
+		from, err := b.personList(ds.OwedFrom)
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		to, err := b.personList(ds.OwedTo)
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		b.duties[ds.ID] = &Duty{ID: ds.ID, Description: ds.Description, OwedFrom: from, OwedTo: to}
+	}
+	for _, ds := range spec.InjuriesOrDamages {
+		if err := b.declare("injury or damage", ds.ID); err != nil {
+			if err := b.check("", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		what := fmt.Sprintf("injury or damage %s", ds.ID)
+		persons, err := b.personList(ds.Persons)
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		var fault []*FaultShare
+		for _, fs := range ds.Fault {
+			p, err := b.person(fs.Person)
+			if err != nil {
+				if err := b.check(what, err); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			fault = append(fault, &FaultShare{Person: p, Percentage: fs.Percentage})
+		}
+		var amounts []*DamageAmount
+		for _, as := range ds.Amounts {
+			category, err := parseDamageCategory(as.Category)
+			if err != nil {
+				if err := b.check(what, err); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			amounts = append(amounts, &DamageAmount{Category: category, Description: as.Description, Dollars: as.Dollars})
+		}
+		switch ds.Type {
+		case bodilyInjuryType:
+			b.damages[ds.ID] = &BodilyInjury{ID: ds.ID, Description: ds.Description, Persons: persons, Amounts: amounts, Fault: fault}
+		case propertyDamageType:
+			b.damages[ds.ID] = &PropertyDamage{ID: ds.ID, Description: ds.Description, Persons: persons, Amounts: amounts, Fault: fault}
+		default:
+			if err := b.check(what, errors.New(fmt.Sprintf("unknown type %q", ds.Type))); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for _, cs := range spec.Claims {
+		if err := b.declare("claim", cs.ID); err != nil {
+			if err := b.check("", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		what := fmt.Sprintf("claim %s", cs.ID)
+		p, err := b.person(cs.Person)
+		if err != nil {
+			// A claim needs a person, so it is left out.
+			if err := b.check(what, err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		e, err := cs.Explanation.build()
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		b.claims[cs.ID] = &Claim{ID: cs.ID, Person: p, Description: cs.Description, Explanation: e}
+	}
+	for _, ds := range spec.Defences {
+		if err := b.declare("defence", ds.ID); err != nil {
+			if err := b.check("", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		what := fmt.Sprintf("defence %s", ds.ID)
+		kind, err := parseDefenceKind(ds.Kind)
+		if err != nil {
+			if err := b.check(what, err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		raisedBy, err := b.personList(ds.RaisedBy)
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		against, err := b.personList(ds.Against)
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		e, err := ds.Explanation.build()
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		b.defences[ds.ID] = &Defence{ID: ds.ID, Kind: kind, Description: ds.Description, RaisedBy: raisedBy, Against: against, Explanation: e}
+	}
+	for _, ls := range spec.BrokenLegalRequirements {
+		if err := b.declare("broken legal requirement", ls.ID); err != nil {
+			if err := b.check("", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		what := fmt.Sprintf("broken legal requirement %s", ls.ID)
+		persons, err := b.personList(ls.Persons)
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		e, err := ls.Explanation.build()
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		protected, err := b.personList(ls.ProtectedPersons)
+		if err := b.check(what, err); err != nil {
+			return nil, nil, err
+		}
+		var harmTypes []HarmType
+		for _, name := range ls.HarmTypes {
+			h, err := parseHarmType(name)
+			if err != nil {
+				if err := b.check(what, err); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			harmTypes = append(harmTypes, h)
+		}
+		b.negPerSe[ls.ID] = &BrokenLegalRequirement{
+			ID:               ls.ID,
+			Description:      ls.Description,
+			Persons:          persons,
+			Explanation:      e,
+			ProtectedClass:   ls.ProtectedClass,
+			ProtectedPersons: protected,
+			HarmTypes:        harmTypes,
+		}
+	}
+	for _, es := range spec.Events {
+		if err := b.declare("event", es.ID); err != nil {
+			if err := b.check("", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		what := fmt.Sprintf("event %s", es.ID)
+		switch es.Type {
+		case actType:
+			act := &Act{ID: es.ID, Description: es.Description}
+			if es.Person != "" {
+				p, err := b.person(es.Person)
+				if err := b.check(what, err); err != nil {
+					return nil, nil, err
+				}
+				act.Person = p
+			}
+			b.events[es.ID] = act
+		case passiveType:
+			if es.Person != "" {
+				if err := b.check(what, errors.New("only acts have a person")); err != nil {
+					return nil, nil, err
+				}
+			}
+			b.events[es.ID] = &PassiveEvent{ID: es.ID, Description: es.Description}
+		default:
+			if err := b.check(what, errors.New(fmt.Sprintf("unknown type %q", es.Type))); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	// Now that all objects exist they can be linked.
+	for _, ls := range spec.BrokenLegalRequirements {
+		blr, ok := b.negPerSe[ls.ID]
+		if !ok {
+			continue
+		}
+		consequences, err := b.eventList(ls.Consequences)
+		if err := b.check(fmt.Sprintf("broken legal requirement %s", ls.ID), err); err != nil {
+			return nil, nil, err
+		}
+		blr.Consequences = consequences
+	}
+	for _, es := range spec.Events {
+		if _, ok := b.events[es.ID]; !ok {
+			continue
+		}
+		if err := b.link(es); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(spec.RootEvents) == 0 {
+		if err := b.check("", errors.New("case without root events")); err != nil {
+			return nil, nil, err
+		}
+	}
+	roots, err := b.eventList(spec.RootEvents)
+	if err := b.check("root events", err); err != nil {
+		return nil, nil, err
+	}
+	subQuestions, err := b.subQuestionTags(spec.SubQuestions)
+	if err != nil {
+		return nil, nil, err
+	}
+	excludedSubQuestions, err := b.subQuestionTags(spec.ExcludedSubQuestions)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &Case{
+		ShortName:            shortName,
+		Text:                 spec.Text,
+		RootEvents:           roots,
+		SubQuestions:         subQuestions,
+		ExcludedSubQuestions: excludedSubQuestions,
+	}
+	for _, rs := range spec.Relationships {
+		kind, err := parseRelationshipKind(rs.Kind)
+		if err != nil {
+			if err := b.check("relationship", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		superior, err := b.person(rs.Superior)
+		if err != nil {
+			if err := b.check("relationship", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		subordinate, err := b.person(rs.Subordinate)
+		if err != nil {
+			if err := b.check("relationship", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		c.Relationships = append(c.Relationships, &Relationship{Kind: kind, Superior: superior, Subordinate: subordinate, Description: rs.Description})
+	}
+	if js := spec.Jurisdiction; js != nil {
+		rule, err := parseNegligenceRule(js.Rule)
+		if err := b.check("jurisdiction", err); err != nil {
+			return nil, nil, err
+		}
+		c.Jurisdiction = &Jurisdiction{Name: js.Name, Rule: rule, AbrogatedCollateralSourceRule: js.AbrogatedCollateralSourceRule}
+	}
+	if err := c.preprocess().err(); err != nil && !lenient {
+		return nil, nil, err
+	}
+	return c, b.problems, nil
+}
+
+// link links an event to its consequences, duty, broken legal
+// requirement, injuries or damages and claims. References to objects
+// that are not known are left out by a lenient builder.
+func (b *caseBuilder) link(es *eventSpec) error {
+	what := fmt.Sprintf("event %s", es.ID)
+	consequences, err := b.eventList(es.Consequences)
+	if err := b.check(what, err); err != nil {
+		return err
+	}
+	var duty *Duty
+	if es.Duty != "" {
+		if duty = b.duties[es.Duty]; duty == nil {
+			if err := b.check(what, errors.New(fmt.Sprintf("unknown duty %s", es.Duty))); err != nil {
+				return err
+			}
+		}
+	}
+	var negPerSe *BrokenLegalRequirement
+	if es.NegPerSe != "" {
+		if negPerSe = b.negPerSe[es.NegPerSe]; negPerSe == nil {
+			if err := b.check(what, errors.New(fmt.Sprintf("unknown broken legal requirement %s", es.NegPerSe))); err != nil {
+				return err
+			}
+		}
+	}
+	var damages []InjuryOrDamage
+	for _, id := range es.InjuriesOrDamages {
+		d, ok := b.damages[id]
+		if !ok {
+			if err := b.check(what, errors.New(fmt.Sprintf("unknown injury or damage %s", id))); err != nil {
+				return err
+			}
+			continue
+		}
+		damages = append(damages, d)
+	}
+	var claims []*Claim
+	for _, id := range es.Claims {
+		c, ok := b.claims[id]
+		if !ok {
+			if err := b.check(what, errors.New(fmt.Sprintf("unknown claim %s", id))); err != nil {
+				return err
+			}
+			continue
+		}
+		claims = append(claims, c)
+	}
+	var defences []*Defence
+	for _, id := range es.Defences {
+		d, ok := b.defences[id]
+		if !ok {
+			if err := b.check(what, errors.New(fmt.Sprintf("unknown defence %s", id))); err != nil {
+				return err
+			}
+			continue
+		}
+		defences = append(defences, d)
+	}
+	foreseeability, err := parseForeseeability(es.Foreseeability)
+	if err := b.check(what, err); err != nil {
+		return err
+	}
+	var resIpsa *ResIpsa
+	if rs := es.ResIpsa; rs != nil {
+		resIpsa = &ResIpsa{UnknownMechanism: rs.UnknownMechanism, OrdinarilyNegligent: rs.OrdinarilyNegligent}
+		if rs.ExclusiveControl != "" {
+			p, err := b.person(rs.ExclusiveControl)
+			if err := b.check(what, err); err != nil {
+				return err
+			}
+			resIpsa.ExclusiveControl = p
+		}
+	}
+
+	switch e := b.events[es.ID].(type) {
+	case *Act:
+		e.Consequences, e.Duty, e.NegPerSe, e.InjuriesOrDamages, e.Claims = consequences, duty, negPerSe, damages, claims
+		e.Defences, e.Foreseeability, e.ResIpsa = defences, foreseeability, resIpsa
+	case *PassiveEvent:
+		e.Consequences, e.Duty, e.NegPerSe, e.InjuriesOrDamages, e.Claims = consequences, duty, negPerSe, damages, claims
+		e.Defences, e.Foreseeability, e.ResIpsa = defences, foreseeability, resIpsa
+	}
+	return nil
+}
+
+// --------------------------------------------------------------------
+
+// Export implements the export command: it writes the content and all
+// concepts to content files, in a form that can be loaded with --content.
+// Concepts go to concepts.<ext>, the multiple choice and proposition
+// questions to questions.<ext> and every case to a file of its own.
+func Export(content *Content, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "", "Directory to write the content files to")
+	format := fs.String("format", "yaml", "Format of the content files: yaml or json")
+	fs.Parse(args)
+	if *out == "" {
+		return errors.New("please specify an output directory with --out")
+	}
+	if *format != "yaml" && *format != "json" {
+		return errors.New(fmt.Sprintf("unknown format %s", *format))
+	}
+
+	content.check()
+	initConcepts()
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+
+	files := make(map[string]*contentFile)
+	files["concepts"] = &contentFile{}
+	for _, c := range allConcepts {
+		files["concepts"].Concepts = append(files["concepts"].Concepts, exportConcept(c))
+	}
+	for _, q := range content.Questions {
+		name := "questions"
+		if _, ok := q.(*Case); ok {
+			name = q.getShortName()
+		}
+		if files[name] == nil {
+			files[name] = &contentFile{}
+		}
+		files[name].Questions = append(files[name].Questions, exportQuestion(q))
+	}
+
+	for name, cf := range files {
+		var data []byte
+		var err error
+		if *format == "json" {
+			data, err = json.MarshalIndent(cf, "", "\t")
+		} else {
+			data, err = yaml.Marshal(cf)
+		}
+		if err != nil {
+			return err
+		}
+		fname := filepath.Join(*out, name+"."+*format)
+		if err := ioutil.WriteFile(fname, data, 0644); err != nil {
+			return err
+		}
+		fmt.Println("Wrote", fname)
+	}
+	return nil
+}
+
+func exportConcepts(concepts []*Concept) []string {
+	if len(concepts) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(concepts))
+	for _, c := range concepts {
+		names = append(names, c.shortName)
+	}
+	return names
+}
+
+func exportExplanation(e *Explanation) *explanationSpec {
+	if e == nil || (len(e.Text) == 0 && len(e.References) == 0) {
+		return nil
+	}
+	spec := &explanationSpec{Text: e.Text}
+	for _, r := range e.References {
+		switch r := r.(type) {
+		case *Restatement:
+			spec.References = append(spec.References, &referenceSpec{Restatement: r.Paragraph})
+		case *URL:
+			spec.References = append(spec.References, &referenceSpec{URL: r.Url})
+		case *Concept:
+			spec.References = append(spec.References, &referenceSpec{Concept: r.shortName})
+		}
+	}
+	return spec
+}
+
+func exportConcept(c *Concept) *conceptSpec {
+	spec := &conceptSpec{
+		ShortName:   c.shortName,
+		Name:        c.name,
+		Level:       c.level,
+		Related:     exportConcepts(c.related),
+		Hints:       c.hints,
+		Explanation: exportExplanation(c.explanation),
+	}
+	if c.bkt != nil {
+		spec.BKT = &bktSpec{PInit: c.bkt.pInit, PLearn: c.bkt.pLearn, PSlip: c.bkt.pSlip, PGuess: c.bkt.pGuess}
+	}
+	return spec
+}
+
+func exportQuestion(q Question) *questionSpec {
+	switch q := q.(type) {
+	case *MultipleChoiceQuestion:
+		spec := &questionSpec{Type: multipleChoiceType, ShortName: q.ShortName, Question: q.Question, Concepts: exportConcepts(q.Concepts)}
+		for _, a := range q.Answers {
+			e := a.Explanation
+			spec.Answers = append(spec.Answers, &answerSpec{
+				Text:           a.Text,
+				Concepts:       exportConcepts(a.Concepts),
+				Explanation:    exportExplanation(&e),
+				Correct:        a.Correct,
+				NoneOfTheAbove: a.NoneOfTheAbove,
+			})
+		}
+		return spec
+	case *PropsQuestion:
+		spec := &questionSpec{Type: propsType, ShortName: q.ShortName}
+		for _, p := range q.Propositions {
+			spec.Propositions = append(spec.Propositions, &propositionSpec{Proposition: p.Proposition, Concepts: exportConcepts(p.Concepts), True: p.True})
+		}
+		return spec
+	case *Case:
+		// The objects without an id get the ids that preprocessing
+		// derived for them.
+		x := newCaseExporter()
+		x.known = q.preprocess().ids
+		return &questionSpec{Type: caseType, ShortName: q.ShortName, Case: x.export(q)}
+	}
+	panic(fmt.Sprintf("Cannot export question %s of type %T", q.getShortName(), q))
+}
+
+// caseExporter assigns ids to the objects in a case while exporting it.
+// Objects that have an id keep it. The other objects get an id made from
+// their name or description. The objects are visited in a fixed order, so
+// that exporting the same case twice gives the same result.
+type caseExporter struct {
+	spec  *caseSpec
+	ids   map[interface{}]string
+	used  map[string]bool
+	known map[identifiable]string // Ids that were already derived for objects.
+}
+
+func newCaseExporter() *caseExporter {
+	return &caseExporter{spec: &caseSpec{}, ids: make(map[interface{}]string), used: make(map[string]bool)}
+}
+
+var nonSlugChars = regexp.MustCompile("[^a-z0-9]+")
+
+// slugWords is the number of words of a description that go into an id.
+const slugWords = 4
+
+// slug makes an id from the first words of a name or description.
+func slug(name string) string {
+	words := strings.Fields(nonSlugChars.ReplaceAllString(strings.ToLower(name), " "))
+	if len(words) > slugWords {
+		words = words[:slugWords]
+	}
+	return strings.Join(words, "_")
+}
+
+// id returns the id of an object: its own id if it has one, the id that
+// was derived for it before, or else a new one.
+func (x *caseExporter) id(obj identifiable, prefix, name string) string {
+	if id := obj.getID(); id != "" {
+		x.used[id] = true
+		return id
+	}
+	if id, ok := x.known[obj]; ok {
+		x.used[id] = true
+		return id
+	}
+	return x.newID(prefix, name)
+}
+
+// newID makes a new unique id from a name or description, or from a prefix
+// if there is no usable one. Ids that are taken get a number.
+func (x *caseExporter) newID(prefix, name string) string {
+	base := slug(name)
+	if base != "" && !x.used[base] {
+		x.used[base] = true
+		return base
+	}
+	if base == "" {
+		base = prefix
+	}
+	for i := 1; ; i++ {
+		id := fmt.Sprintf("%s%d", base, i)
+		if !x.used[id] {
+			x.used[id] = true
+			return id
+		}
+	}
+}
+
+func (x *caseExporter) person(p *Person) string {
+	if id, ok := x.ids[p]; ok {
+		return id
+	}
+	id := x.id(p, "person", p.Name)
+	x.ids[p] = id
+	x.spec.Persons = append(x.spec.Persons, &personSpec{ID: id, Name: p.Name})
+	return id
+}
+
+func (x *caseExporter) persons(persons []*Person) []string {
+	ids := make([]string, 0, len(persons))
+	for _, p := range persons {
+		ids = append(ids, x.person(p))
+	}
+	return ids
+}
+
+func (x *caseExporter) duty(d *Duty) string {
+	if id, ok := x.ids[d]; ok {
+		return id
+	}
+	id := x.id(d, "duty", d.Description)
+	x.ids[d] = id
+	x.spec.Duties = append(x.spec.Duties, &dutySpec{ID: id, Description: d.Description, OwedFrom: x.persons(d.OwedFrom), OwedTo: x.persons(d.OwedTo)})
+	return id
+}
+
+func (x *caseExporter) damage(d InjuryOrDamage) string {
+	if id, ok := x.ids[d]; ok {
+		return id
+	}
+	id := x.id(d, "damage", d.GetDescription())
+	x.ids[d] = id
+	spec := &damageSpec{ID: id, Description: d.GetDescription(), Persons: x.persons(d.GetPersons())}
+	for _, a := range d.getAmounts() {
+		spec.Amounts = append(spec.Amounts, &amountSpec{Category: damageCategoryNames[a.Category], Description: a.Description, Dollars: a.Dollars})
+	}
+	for _, f := range d.getFault() {
+		spec.Fault = append(spec.Fault, &faultShareSpec{Person: x.person(f.Person), Percentage: f.Percentage})
+	}
+	switch d.(type) {
+	case *BodilyInjury:
+		spec.Type = bodilyInjuryType
+	case *PropertyDamage:
+		spec.Type = propertyDamageType
+	}
+	x.spec.InjuriesOrDamages = append(x.spec.InjuriesOrDamages, spec)
+	return id
+}
+
+func (x *caseExporter) claim(c *Claim) string {
+	if id, ok := x.ids[c]; ok {
+		return id
+	}
+	id := x.id(c, "claim", c.Description)
+	x.ids[c] = id
+	x.spec.Claims = append(x.spec.Claims, &claimSpec{ID: id, Person: x.person(c.Person), Description: c.Description, Explanation: exportExplanation(c.Explanation)})
+	return id
+}
+
+func (x *caseExporter) defence(d *Defence) string {
+	if id, ok := x.ids[d]; ok {
+		return id
+	}
+	id := x.id(d, "defence", d.Description)
+	x.ids[d] = id
+	x.spec.Defences = append(x.spec.Defences, &defenceSpec{
+		ID:          id,
+		Kind:        defenceKindNames[d.Kind],
+		Description: d.Description,
+		RaisedBy:    x.persons(d.RaisedBy),
+		Against:     x.persons(d.Against),
+		Explanation: exportExplanation(d.Explanation),
+	})
+	return id
+}
+
+func (x *caseExporter) negPerSe(b *BrokenLegalRequirement) string {
+	if id, ok := x.ids[b]; ok {
+		return id
+	}
+	id := x.id(b, "negperse", b.Description)
+	x.ids[b] = id
+	spec := &legalRequirementSpec{
+		ID:             id,
+		Description:    b.Description,
+		Persons:        x.persons(b.Persons),
+		Explanation:    exportExplanation(b.Explanation),
+		ProtectedClass: b.ProtectedClass,
+	}
+	if len(b.ProtectedPersons) > 0 {
+		spec.ProtectedPersons = x.persons(b.ProtectedPersons)
+	}
+	for _, h := range b.HarmTypes {
+		spec.HarmTypes = append(spec.HarmTypes, harmTypeNames[h])
+	}
+	x.spec.BrokenLegalRequirements = append(x.spec.BrokenLegalRequirements, spec)
+	spec.Consequences = x.events(b.Consequences)
+	return id
+}
+
+func (x *caseExporter) events(events []Event) []string {
+	if len(events) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, x.event(e))
+	}
+	return ids
+}
+
+func (x *caseExporter) event(e Event) string {
+	if id, ok := x.ids[e]; ok {
+		return id
+	}
+	id := x.id(e, "event", e.getDescription())
+	x.ids[e] = id
+	spec := &eventSpec{ID: id, Type: passiveType, Description: e.getDescription()}
+	if f := e.getForeseeability(); f != Foreseeable {
+		spec.Foreseeability = foreseeabilityNames[f]
+	}
+	if r := e.getResIpsa(); r != nil {
+		spec.ResIpsa = &resIpsaSpec{UnknownMechanism: r.UnknownMechanism, OrdinarilyNegligent: r.OrdinarilyNegligent}
+		if r.ExclusiveControl != nil {
+			spec.ResIpsa.ExclusiveControl = x.person(r.ExclusiveControl)
+		}
+	}
+	if act, ok := e.(*Act); ok {
+		spec.Type = actType
+		if act.Person != nil {
+			spec.Person = x.person(act.Person)
+		}
+	}
+	x.spec.Events = append(x.spec.Events, spec)
+	if e.getDuty() != nil {
+		spec.Duty = x.duty(e.getDuty())
+	}
+	for _, d := range e.getInjuriesOrDamages() {
+		spec.InjuriesOrDamages = append(spec.InjuriesOrDamages, x.damage(d))
+	}
+	for _, c := range e.getClaims() {
+		spec.Claims = append(spec.Claims, x.claim(c))
+	}
+	for _, d := range e.getDefences() {
+		spec.Defences = append(spec.Defences, x.defence(d))
+	}
+	if e.getNegPerSe() != nil {
+		spec.NegPerSe = x.negPerSe(e.getNegPerSe())
+	}
+	spec.Consequences = x.events(e.getConsequences())
+	return id
+}
+
+func (x *caseExporter) export(c *Case) *caseSpec {
+	x.spec.Text = c.Text
+	x.spec.RootEvents = x.events(c.RootEvents)
+	x.spec.SubQuestions = c.SubQuestions
+	x.spec.ExcludedSubQuestions = c.ExcludedSubQuestions
+	for _, r := range c.Relationships {
+		x.spec.Relationships = append(x.spec.Relationships, &relationshipSpec{
+			Kind:        relationshipKindNames[r.Kind],
+			Superior:    x.person(r.Superior),
+			Subordinate: x.person(r.Subordinate),
+			Description: r.Description,
+		})
+	}
+	if c.Jurisdiction != nil {
+		x.spec.Jurisdiction = &jurisdictionSpec{Name: c.Jurisdiction.Name, Rule: negligenceRuleNames[c.Jurisdiction.Rule], AbrogatedCollateralSourceRule: c.Jurisdiction.AbrogatedCollateralSourceRule}
+	}
+	return x.spec
+}
@@ -0,0 +1,94 @@
+package nits
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const testContent = `
+concepts:
+- shortName: filetest1
+  name: content file test
+  level: 1
+  related: [negperse1]
+  bkt: {pInit: 0.2, pLearn: 0}
+questions:
+- type: multipleChoice
+  shortName: mc_file
+  question: ["Is this a question?"]
+  concepts: [filetest1]
+  answers:
+  - {text: "Yes", correct: true}
+  - {text: "No", concepts: [negperse1]}
+- type: case
+  shortName: case_file
+  case:
+    text: ["Alice drives into Bob."]
+    rootEvents: [drives]
+    persons:
+    - {id: alice, name: Alice}
+    - {id: bob, name: Bob}
+    duties:
+    - {id: drive_carefully, description: Drive carefully, owedFrom: [alice], owedTo: [bob]}
+    injuriesOrDamages:
+    - {id: bobs_leg, type: bodilyInjury, description: Bob breaks his leg, persons: [bob]}
+    events:
+    - {id: drives, type: act, person: alice, description: Alice drives into Bob, duty: drive_carefully, consequences: [falls]}
+    - {id: falls, type: passive, description: Bob falls, injuriesOrDamages: [bobs_leg]}
+`
+
+func TestLoadContent(t *testing.T) {
+	n := len(allConcepts)
+	defer func() { allConcepts = allConcepts[:n] }()
+	dir, err := ioutil.TempDir("", "nits_content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(path.Join(dir, "test.yaml"), []byte(testContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	content, err := LoadContent(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content.check()
+	if len(content.Questions) != 2 {
+		t.Fatalf("LoadContent(); got: %d questions, want: 2", len(content.Questions))
+	}
+	c := lookupConcept("filetest1")
+	if c == nil || c.getBKTParams().pInit != 0.2 || c.getBKTParams().pLearn != 0 || len(c.related) != 1 {
+		t.Errorf("LoadContent(); concept filetest1 not loaded correctly: %v", c)
+	}
+	pp := content.Questions[1].(*Case).preprocess()
+	if len(pp.events) != 2 || len(pp.persons) != 2 || len(pp.duties) != 1 || len(pp.injuriesOrDamages) != 1 {
+		t.Errorf("LoadContent(); case not loaded correctly: %v", pp)
+	}
+	if pp.findEvent("falls").getDirectCauses()[0] != pp.findEvent("drives") {
+		t.Errorf("LoadContent(); events not linked")
+	}
+
+	// BKT parameters must be probabilities, and only pLearn can be 0 or 1.
+	bad := strings.Replace(testContent, "pInit: 0.2", "pInit: 1", 1)
+	if err := ioutil.WriteFile(path.Join(dir, "test.yaml"), []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+	allConcepts = allConcepts[:n]
+	if _, err := LoadContent(dir); err == nil || !strings.Contains(err.Error(), "pInit 1 is not between 0 and 1") {
+		t.Errorf("LoadContent() with pInit 1; got: %v, want: an error", err)
+	}
+
+	// References to unknown objects are errors.
+	bad = strings.Replace(testContent, "duty: drive_carefully", "duty: drive_slowly", 1)
+	if err := ioutil.WriteFile(path.Join(dir, "test.yaml"), []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+	allConcepts = allConcepts[:n]
+	if _, err := LoadContent(dir); err == nil || !strings.Contains(err.Error(), "unknown duty drive_slowly") {
+		t.Errorf("LoadContent(); got: %v, want: unknown duty error", err)
+	}
+}
@@ -0,0 +1,142 @@
+package nits
+
+// This file implements the damages sub question. It asks the student how
+// much a plaintiff can recover for an injury or damage, given the amounts
+// that make up the damages.
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// recovery computes the amount that can be recovered for an injury or
+// damage, before apportionment. It also returns the steps of the
+// computation, for explaining it to the student.
+func recovery(j *Jurisdiction, dam InjuryOrDamage) (int, []string) {
+	total := 0
+	steps := make([]string, 0)
+	for _, a := range dam.getAmounts() {
+		switch a.Category {
+		case Economic:
+			total += a.Dollars
+			steps = append(steps, fmt.Sprintf("+ $%d: %s (economic damages)", a.Dollars, a.Description))
+		case NonEconomic:
+			total += a.Dollars
+			steps = append(steps, fmt.Sprintf("+ $%d: %s (non-economic damages)", a.Dollars, a.Description))
+		case Punitive:
+			total += a.Dollars
+			steps = append(steps, fmt.Sprintf("+ $%d: %s (punitive damages come on top of the compensatory damages)", a.Dollars, a.Description))
+		case CollateralSource:
+			if j != nil && j.AbrogatedCollateralSourceRule {
+				total -= a.Dollars
+				steps = append(steps, fmt.Sprintf("- $%d: %s (%s has abrogated the collateral source rule, so this reduces the damages)", a.Dollars, a.Description, j.Name))
+			} else {
+				steps = append(steps, fmt.Sprintf("  $%d: %s (under the collateral source rule this does not reduce the damages)", a.Dollars, a.Description))
+			}
+		}
+	}
+	steps = append(steps, fmt.Sprintf("= $%d", total))
+	return total, steps
+}
+
+// compensatory returns the compensatory damages for an injury or damage:
+// what can be recovered for it without the punitive damages. These are
+// the damages that are apportioned by fault.
+func compensatory(j *Jurisdiction, dam InjuryOrDamage) int {
+	total, _ := recovery(j, dam)
+	for _, a := range dam.getAmounts() {
+		if a.Category == Punitive {
+			total -= a.Dollars
+		}
+	}
+	return total
+}
+
+// valuedDamages returns the damages in a case that have amounts.
+func (p *preprocessedCase) valuedDamages() []InjuryOrDamage {
+	result := make([]InjuryOrDamage, 0)
+	for dam := range p.injuriesOrDamages {
+		if len(dam.getAmounts()) > 0 && len(dam.GetPersons()) > 0 {
+			result = append(result, dam)
+		}
+	}
+	p.sortDamages(result)
+	return result
+}
+
+type damagesSubQuestion struct{}
+
+func (d *damagesSubQuestion) getTag() string {
+	return "damages"
+}
+
+func (d *damagesSubQuestion) getConcepts() []*Concept {
+	return []*Concept{EconomicDamages1, PunitiveDamages1, CollateralSourcePayments1}
+}
+
+func (d *damagesSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.valuedDamages()) > 0
+}
+
+var _ = addSubQuestion(&damagesSubQuestion{})
+
+// newInstance picks a random damage and a person who suffered it.
+func (d *damagesSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	dams := pp.valuedDamages()
+	if len(dams) == 0 {
+		return nil
+	}
+	dam := dams[r.Intn(len(dams))]
+	return pp.newInstance(d, dam, dam.GetPersons()[r.Intn(len(dam.GetPersons()))])
+}
+
+// ask asks the damages sub question.
+func (d *damagesSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	dam, ok1 := pp.ref(inst, 0).(InjuryOrDamage)
+	plaintiff, ok2 := pp.ref(inst, 1).(*Person)
+	if !ok1 || !ok2 {
+		return badInstance(ui, inst)
+	}
+	rightAnswer, steps := recovery(c.Jurisdiction, dam)
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		if c.Jurisdiction != nil {
+			ui.println("This case is tried in %s.", c.Jurisdiction.Name)
+		}
+		ui.println("Consider this injury or property damage:")
+		ui.println(dam.GetDescription())
+		ui.println("The following amounts are involved:")
+		for _, a := range dam.getAmounts() {
+			ui.println("- %s: $%d", a.Description, a.Dollars)
+		}
+		ui.println("How much can %s recover, not taking into account any fault of %s?", plaintiff.Name, plaintiff.Name)
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses := make([]string, 0)
+
+	for {
+		answer, ret := ui.getAmount()
+		if ret {
+			return ret
+		}
+		responses = append(responses, fmt.Sprintf("%d", answer))
+		if answer == rightAnswer {
+			ui.println("Correct :-)")
+			break
+		}
+		ui.println("Please try again :-(")
+	}
+	for _, s := range steps {
+		ui.println("%s", s)
+	}
+	state.registerAnswer(c, d, responses)
+	return false
+}
@@ -0,0 +1,24 @@
+package nits
+
+import "testing"
+
+func TestRecovery(t *testing.T) {
+	dam := &BodilyInjury{Amounts: []*DamageAmount{
+		{Category: Economic, Dollars: 100},
+		{Category: NonEconomic, Dollars: 20},
+		{Category: Punitive, Dollars: 3},
+		{Category: CollateralSource, Dollars: 50},
+	}}
+	if got := compensatory(nil, dam); got != 120 {
+		t.Errorf("compensatory(nil); got: %d, want: 120", got)
+	}
+	if got := compensatory(&Jurisdiction{AbrogatedCollateralSourceRule: true}, dam); got != 70 {
+		t.Errorf("compensatory(abrogated); got: %d, want: 70", got)
+	}
+	if got, _ := recovery(nil, dam); got != 123 {
+		t.Errorf("recovery(nil); got: %d, want: 123", got)
+	}
+	if got, _ := recovery(&Jurisdiction{AbrogatedCollateralSourceRule: true}, dam); got != 73 {
+		t.Errorf("recovery(abrogated); got: %d, want: 73", got)
+	}
+}
@@ -2,9 +2,20 @@ package nits
 
 // This file contains the core data model of NITS.
 
+// --------------------------------------------------------------------
+// identifiable is an object in the graph of a case. Every object has an id
+// that is unique in its case and that does not change between runs, so
+// that it can be referred to from content files, saved answers, dot
+// drawings and debug commands. Objects without an authored id get a
+// derived one when the case is preprocessed.
+type identifiable interface {
+	getID() string
+}
+
 // --------------------------------------------------------------------
 // Person is a person that is involved in a case.
 type Person struct {
+	ID      string
 	Name    string
 	damages map[InjuryOrDamage]interface{}
 }
@@ -13,24 +24,93 @@ func (p *Person) getLabel() string {
 	return p.Name
 }
 
+func (p *Person) getID() string {
+	return p.ID
+}
+
+// --------------------------------------------------------------------
+// RelationshipKind is the kind of a relationship between two persons.
+type RelationshipKind int
+
+const (
+	Employment RelationshipKind = iota // The superior employs the subordinate.
+	Agency                             // The subordinate acts as an agent of the superior.
+	Ownership                          // The subordinate uses property that the superior owns.
+)
+
+// Relationship is a relationship between two persons that can make one of
+// them vicariously liable for the acts of the other.
+type Relationship struct {
+	Kind        RelationshipKind
+	Superior    *Person // The employer, principal or owner.
+	Subordinate *Person // The employee, agent or user of the property.
+	Description string
+}
+
+func (r *Relationship) getLabel() string {
+	return r.Description
+}
+
+// String returns a description of a kind of relationship.
+func (k RelationshipKind) String() string {
+	switch k {
+	case Employment:
+		return "employs"
+	case Agency:
+		return "is the principal of"
+	case Ownership:
+		return "owns the property used by"
+	}
+	return "is related to"
+}
+
 // --------------------------------------------------------------------
 // BrokenLegalRequirement is the fact that one or more persons are in
 // violation of a statute ir regulation (negligence per se).
 type BrokenLegalRequirement struct {
-	Description  string
-	Persons      []*Person
-	Consequences []Event
-	Explanation  *Explanation
-	event        Event // Back link to event that points to this BrokenLegalRequirement.
+	ID               string
+	Description      string
+	Persons          []*Person
+	Consequences     []Event
+	Explanation      *Explanation
+	ProtectedClass   string     // Description of the class of people the statute protects.
+	ProtectedPersons []*Person  // The persons in the case that are in the protected class.
+	HarmTypes        []HarmType // The types of harm the statute is meant to prevent.
+	event            Event      // Back link to event that points to this BrokenLegalRequirement.
+}
+
+// HarmType is a type of harm that a statute or regulation is meant to
+// prevent.
+type HarmType int
+
+const (
+	BodilyHarm   HarmType = iota // Bodily injuries.
+	PropertyHarm                 // Damage to property.
+)
+
+// String returns a description of a type of harm.
+func (h HarmType) String() string {
+	switch h {
+	case BodilyHarm:
+		return "bodily injury"
+	case PropertyHarm:
+		return "property damage"
+	}
+	return "unknown harm"
 }
 
 func (b *BrokenLegalRequirement) getLabel() string {
 	return b.Description
 }
 
+func (b *BrokenLegalRequirement) getID() string {
+	return b.ID
+}
+
 // --------------------------------------------------------------------
 // Duty is a legal obligation.
 type Duty struct {
+	ID          string
 	Description string
 	OwedFrom    []*Person
 	OwedTo      []*Person
@@ -41,9 +121,14 @@ func (d *Duty) getLabel() string {
 	return d.Description
 }
 
+func (d *Duty) getID() string {
+	return d.ID
+}
+
 // --------------------------------------------------------------------
 // Claim is an (irrelevant) claim that a person is making for an event.
 type Claim struct {
+	ID          string
 	Person      *Person
 	Description string
 	Explanation *Explanation
@@ -54,11 +139,89 @@ func (c *Claim) getLabel() string {
 	return c.Description
 }
 
+func (c *Claim) getID() string {
+	return c.ID
+}
+
+// --------------------------------------------------------------------
+// DefenceKind is the kind of a defence.
+type DefenceKind int
+
+const (
+	AssumptionOfRisk              DefenceKind = iota // The plaintiff knowingly accepted the risk.
+	ContributoryNegligenceDefence                    // The plaintiff's own negligence contributed to the harm.
+	StatuteOfLimitations                             // The plaintiff waited too long to sue.
+)
+
+// defenceKinds are all kinds of defences, in the order they are shown to
+// the student.
+var defenceKinds = []DefenceKind{AssumptionOfRisk, ContributoryNegligenceDefence, StatuteOfLimitations}
+
+// String returns the name of a kind of defence.
+func (k DefenceKind) String() string {
+	switch k {
+	case AssumptionOfRisk:
+		return "assumption of risk"
+	case ContributoryNegligenceDefence:
+		return "contributory negligence"
+	case StatuteOfLimitations:
+		return "statute of limitations"
+	}
+	return "unknown defence"
+}
+
+// Defence is a defence that defendants can raise against plaintiffs
+// because of an event.
+type Defence struct {
+	ID          string
+	Kind        DefenceKind
+	Description string
+	RaisedBy    []*Person // The defendants that can raise the defence.
+	Against     []*Person // The plaintiffs it can be raised against.
+	Explanation *Explanation
+	event       Event // Back link to the event that points to this Defence.
+}
+
+func (d *Defence) getLabel() string {
+	return d.Description
+}
+
+func (d *Defence) getID() string {
+	return d.ID
+}
+
+// --------------------------------------------------------------------
+// Foreseeability says how an event relates to the events that caused it,
+// for the purpose of proximate cause.
+type Foreseeability int
+
+const (
+	Foreseeable   Foreseeability = iota // A foreseeable consequence of its causes (the default).
+	Unforeseeable                       // Not a foreseeable consequence; breaks the chain of proximate cause.
+	Intervening                         // An intervening cause that does not break the chain.
+	Superseding                         // An intervening cause that breaks the chain.
+)
+
+// breaksChain tells if an event with this foreseeability cuts off
+// proximate cause from the events before it.
+func (f Foreseeability) breaksChain() bool {
+	return f == Unforeseeable || f == Superseding
+}
+
+// ResIpsa marks an event for the purpose of res ipsa loquitur: the
+// doctrine that lets a plaintiff infer negligence from the mere fact that
+// the event happened.
+type ResIpsa struct {
+	UnknownMechanism    bool    // It is not known how exactly the event came about.
+	OrdinarilyNegligent bool    // Events like this do not ordinarily happen without negligence.
+	ExclusiveControl    *Person // The person in exclusive control of what caused the event, if any.
+}
+
 // --------------------------------------------------------------------
 // Event is something that happened.
 type Event interface {
+	identifiable
 	getLabel() string // For dot drawings.
-	getShortName() string
 	getDescription() string
 	getConsequences() []Event
 	getDuty() *Duty
@@ -66,18 +229,38 @@ type Event interface {
 	getInjuriesOrDamages() []InjuryOrDamage
 	getDirectCauses() []Event
 	getClaims() []*Claim
+	getDefences() []*Defence
+	getForeseeability() Foreseeability
+	getResIpsa() *ResIpsa
 	addCause(e Event)
 }
 
+// addEvent adds an event to a list of events, unless it is nil or
+// already in the list.
+func addEvent(events []Event, e Event) []Event {
+	if e == nil {
+		return events
+	}
+	for _, other := range events {
+		if other == e {
+			return events
+		}
+	}
+	return append(events, e)
+}
+
 // PassiveEvent is an event that just happens, it is not an Act.
 type PassiveEvent struct {
-	shortName         string // for internal (testing) use only.
+	ID                string
 	Description       string
 	Consequences      []Event
 	Duty              *Duty
 	NegPerSe          *BrokenLegalRequirement
 	InjuriesOrDamages []InjuryOrDamage
 	Claims            []*Claim
+	Defences          []*Defence
+	Foreseeability    Foreseeability
+	ResIpsa           *ResIpsa
 	directCauses      []Event // Back links to the events that this event is a consequence of.
 }
 
@@ -87,17 +270,11 @@ func (pe *PassiveEvent) getLabel() string {
 
 // addCause adds an event that is the cause of this event.
 func (pe *PassiveEvent) addCause(event Event) {
-	if event == nil {
-		return
-	}
-	if pe.directCauses == nil {
-		pe.directCauses = make([]Event, 0, 1)
-	}
-	pe.directCauses = append(pe.directCauses, event)
+	pe.directCauses = addEvent(pe.directCauses, event)
 }
 
-func (pe *PassiveEvent) getShortName() string {
-	return pe.shortName
+func (pe *PassiveEvent) getID() string {
+	return pe.ID
 }
 
 func (pe *PassiveEvent) getDescription() string {
@@ -124,14 +301,26 @@ func (pe *PassiveEvent) getClaims() []*Claim {
 	return pe.Claims
 }
 
+func (pe *PassiveEvent) getDefences() []*Defence {
+	return pe.Defences
+}
+
 func (pe *PassiveEvent) getDirectCauses() []Event {
 	return pe.directCauses
 }
 
+func (pe *PassiveEvent) getForeseeability() Foreseeability {
+	return pe.Foreseeability
+}
+
+func (pe *PassiveEvent) getResIpsa() *ResIpsa {
+	return pe.ResIpsa
+}
+
 // --------------------------------------------------------------------
 // Act is an event that was a willful act by a person.
 type Act struct {
-	shortName         string // Internal use (testing) only.
+	ID                string
 	Person            *Person
 	Description       string
 	Consequences      []Event
@@ -139,6 +328,9 @@ type Act struct {
 	NegPerSe          *BrokenLegalRequirement
 	InjuriesOrDamages []InjuryOrDamage
 	Claims            []*Claim
+	Defences          []*Defence
+	Foreseeability    Foreseeability
+	ResIpsa           *ResIpsa
 	directCauses      []Event // Back links to the events that inspired this act.
 }
 
@@ -147,17 +339,11 @@ func (a *Act) getLabel() string {
 }
 
 func (a *Act) addCause(event Event) {
-	if event == nil {
-		return
-	}
-	if a.directCauses == nil {
-		a.directCauses = make([]Event, 0, 1)
-	}
-	a.directCauses = append(a.directCauses, event)
+	a.directCauses = addEvent(a.directCauses, event)
 }
 
-func (a *Act) getShortName() string {
-	return a.shortName
+func (a *Act) getID() string {
+	return a.ID
 }
 
 func (a *Act) getDescription() string {
@@ -184,25 +370,90 @@ func (a *Act) getClaims() []*Claim {
 	return a.Claims
 }
 
+func (a *Act) getDefences() []*Defence {
+	return a.Defences
+}
+
 func (a *Act) getDirectCauses() []Event {
 	return a.directCauses
 }
 
+func (a *Act) getForeseeability() Foreseeability {
+	return a.Foreseeability
+}
+
+func (a *Act) getResIpsa() *ResIpsa {
+	return a.ResIpsa
+}
+
+// --------------------------------------------------------------------
+// NegligenceRule is the rule that a jurisdiction uses to apportion damages
+// when the plaintiff is partly at fault.
+type NegligenceRule int
+
+const (
+	NoNegligenceRule      NegligenceRule = iota // The case does not apportion damages.
+	PureComparative                             // Recovery is reduced by the plaintiff's share.
+	ModifiedComparative50                       // Like pure, but a share of 50% or more bars recovery.
+	ModifiedComparative51                       // Like pure, but a share of 51% or more bars recovery.
+	Contributory                                // Any fault of the plaintiff bars recovery.
+)
+
+// Jurisdiction is the jurisdiction in which a case is tried.
+type Jurisdiction struct {
+	Name string
+	Rule NegligenceRule
+	// Many states have abrogated the collateral source rule by statute;
+	// there collateral source payments reduce the damages.
+	AbrogatedCollateralSourceRule bool
+}
+
+// FaultShare is the percentage of the fault for an injury or damage that
+// is attributed to a person.
+type FaultShare struct {
+	Person     *Person
+	Percentage int
+}
+
+// DamageCategory is the category of an amount of damages.
+type DamageCategory int
+
+const (
+	Economic         DamageCategory = iota // Monetary losses, like medical bills.
+	NonEconomic                            // Losses like pain and suffering.
+	Punitive                               // Damages to punish the defendant.
+	CollateralSource                       // Payments from a source independent of the defendant, like insurance.
+)
+
+// DamageAmount is an amount of money that is part of the damages.
+type DamageAmount struct {
+	Category    DamageCategory
+	Description string
+	Dollars     int
+}
+
 // --------------------------------------------------------------------
 // InjuryOrDamage; speaks for itself :-)
 type InjuryOrDamage interface {
+	identifiable
 	GetDescription() string
 	GetPersons() []*Person
 	getLabel() string
 	getDirectCauses() []Event
 	addCause(event Event)
+	getAmounts() []*DamageAmount
+	getFault() []*FaultShare
+	getHarmType() HarmType
 }
 
 // BodilyInjury is a bodily injury suffered by one or more persons.
 type BodilyInjury struct {
+	ID           string
 	Description  string
 	Persons      []*Person
-	directCauses []Event // Back links to the events that directly caused this injury.
+	Amounts      []*DamageAmount
+	Fault        []*FaultShare // Who is to blame, and how much.
+	directCauses []Event       // Back links to the events that directly caused this injury.
 }
 
 func (b *BodilyInjury) GetDescription() string {
@@ -213,26 +464,42 @@ func (b *BodilyInjury) getLabel() string {
 	return b.Description
 }
 
+func (b *BodilyInjury) getID() string {
+	return b.ID
+}
+
 func (b *BodilyInjury) GetPersons() []*Person {
 	return b.Persons
 }
 
 func (b *BodilyInjury) addCause(event Event) {
-	if b.directCauses == nil {
-		b.directCauses = make([]Event, 0, 1)
-	}
-	b.directCauses = append(b.directCauses, event)
+	b.directCauses = addEvent(b.directCauses, event)
 }
 
 func (b *BodilyInjury) getDirectCauses() []Event {
 	return b.directCauses
 }
 
+func (b *BodilyInjury) getAmounts() []*DamageAmount {
+	return b.Amounts
+}
+
+func (b *BodilyInjury) getFault() []*FaultShare {
+	return b.Fault
+}
+
+func (b *BodilyInjury) getHarmType() HarmType {
+	return BodilyHarm
+}
+
 // PropertyDamage is damage to somebody's property.
 type PropertyDamage struct {
+	ID           string
 	Description  string
 	Persons      []*Person
-	directCauses []Event // Back links to the events that directly caused this damage.
+	Amounts      []*DamageAmount
+	Fault        []*FaultShare // Who is to blame, and how much.
+	directCauses []Event       // Back links to the events that directly caused this damage.
 }
 
 func (p *PropertyDamage) GetDescription() string {
@@ -243,26 +510,40 @@ func (p *PropertyDamage) getLabel() string {
 	return p.Description
 }
 
+func (p *PropertyDamage) getID() string {
+	return p.ID
+}
+
 func (p *PropertyDamage) GetPersons() []*Person {
 	return p.Persons
 }
 
 func (p *PropertyDamage) addCause(event Event) {
-	if p.directCauses == nil {
-		p.directCauses = make([]Event, 0, 1)
-	}
-	p.directCauses = append(p.directCauses, event)
+	p.directCauses = addEvent(p.directCauses, event)
 }
 
 func (p *PropertyDamage) getDirectCauses() []Event {
 	return p.directCauses
 }
 
+func (p *PropertyDamage) getAmounts() []*DamageAmount {
+	return p.Amounts
+}
+
+func (p *PropertyDamage) getFault() []*FaultShare {
+	return p.Fault
+}
+
+func (p *PropertyDamage) getHarmType() HarmType {
+	return PropertyHarm
+}
+
 // --------------------------------------------------------------------
 
 // Content is the question content that NITS operates on.
 type Content struct {
 	Questions []Question
+	sources   map[string]string // Content file per question short name, if loaded from files.
 }
 
 // findQuestion finds a question by short name.
@@ -4,8 +4,13 @@ package nits
 // can be used to get an insight into the internals of NITS.
 
 import (
+	"encoding/json"
 	"fmt"
 	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // A global tracer. When non-nil this can be used to get some debugging
@@ -36,15 +41,39 @@ func displayAnswers(ui *userInterface, state *studentState) {
 	ui.println("Registered answers:")
 	ui.newline()
 
-	for _, answer := range state.answers {
-		ui.print("%5t: %s", answer.correct, answer.questionShortName)
+	for i, answer := range state.answers {
+		ui.print("%3d %5t: %s", i+1, answer.correct, answer.questionShortName)
 		if answer.subQuestion != nil {
-			ui.print("#%s", answer.subQuestion)
+			ui.print("#%s", answer.subQuestion.getTag())
+		}
+		if len(answer.instance) > 0 {
+			ui.print("(%s)", strings.Join(answer.instance, ", "))
+		}
+		if len(answer.responses) > 0 {
+			ui.print(" (%d attempts in %s: %s)", len(answer.responses), answer.duration.Round(time.Second), strings.Join(answer.responses, " / "))
 		}
 		ui.newline()
 	}
 }
 
+// displayParams is a UI command that displays the effective BKT parameters
+// of all concepts. Concepts that declare their own parameters are marked
+// with a *.
+func displayParams(ui *userInterface) {
+	ui.println("BKT parameters (backend: %s, fit on student answers: %t):", backend.getName(), *bktFitFlag)
+	ui.newline()
+	ui.println("%-20s %8s %8s %8s %8s", "concept", "pInit", "pLearn", "pSlip", "pGuess")
+
+	for _, c := range allConcepts {
+		p := c.getBKTParams()
+		custom := ""
+		if c.bkt != nil {
+			custom = "*"
+		}
+		ui.println("%-20s %8.3f %8.3f %8.3f %8.3f %s", c.shortName, p.pInit, p.pLearn, p.pSlip, p.pGuess, custom)
+	}
+}
+
 // displayQuestions is a UI command that displays all questions in the content.
 func displayQuestions(ui *userInterface, state *studentState) {
 	ui.println("All questions:")
@@ -90,6 +119,72 @@ func nextQuestion(ui *userInterface, state *studentState, words []string) {
 	}
 }
 
+// displayObjects is a UI command that displays all the objects in a case
+// (by short name) with their ids.
+func displayObjects(ui *userInterface, state *studentState, words []string) {
+	if len(words) < 2 {
+		ui.error("Please specify the short name of a case.")
+		return
+	}
+	c, ok := state.content.findQuestion(words[1]).(*Case)
+	if !ok {
+		ui.error("Case not found.")
+		return
+	}
+	pp := c.preprocess()
+	ids := make([]string, 0, len(pp.objects))
+	for id := range pp.objects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		ui.println("%-24s %T: %s", id, pp.objects[id], pp.objects[id].(hasLabel).getLabel())
+	}
+}
+
+// reask is a UI command that asks the sub question instance of a
+// registered answer (by its number in the list of answers) again. The
+// answer is not registered.
+func reask(ui *userInterface, state *studentState, words []string) {
+	if len(words) < 2 {
+		ui.error("Please specify the number of an answer.")
+		return
+	}
+	n, err := strconv.Atoi(words[1])
+	if err != nil || n < 1 || n > len(state.answers) {
+		ui.error("Answer not found.")
+		return
+	}
+	a := state.answers[n-1]
+	c, ok := a.question.(*Case)
+	if !ok || a.subQuestion == nil {
+		ui.error("Answer %d is not an answer to a sub question of a case.", n)
+		return
+	}
+	inst, err := c.findInstance(a.subQuestion, a.instance)
+	if err != nil {
+		ui.error("Can not ask answer %d again: %s", n, err)
+		return
+	}
+	// The instance is asked on a throwaway state, so that answering it
+	// again does not change the student's data.
+	model, err := newStudentModel(state.model.getName())
+	if err != nil {
+		ui.error("Can not ask answer %d again: %s", n, err)
+		return
+	}
+	// The answer might be from an earlier session; its seed replays it.
+	scratch := newStudentState(state.content, model)
+	if a.seed != nil {
+		scratch.setSeed(*a.seed)
+	} else {
+		scratch.setSeed(state.seed)
+	}
+	c.withContext(ui, scratch, func() {
+		c.askInstance(ui, scratch, inst)
+	})
+}
+
 // debug is the NITS debugger UI command.
 func debug(ui *userInterface, state *studentState, words []string) bool {
 	ui.pushCommandContext(&CommandContext{
@@ -119,6 +214,30 @@ func debug(ui *userInterface, state *studentState, words []string) bool {
 					return false
 				},
 			},
+			{
+				aliases: []string{"objects"},
+				help:    "Displays the objects in a case (by short name) with their ids.",
+				executor: func(words []string) bool {
+					displayObjects(ui, state, words)
+					return false
+				},
+			},
+			{
+				aliases: []string{"seed"},
+				help:    "Shows the seed of the random number generator of this session.",
+				executor: func([]string) bool {
+					ui.println("Seed: %d (replay this session with --seed %d)", state.seed, state.seed)
+					return false
+				},
+			},
+			{
+				aliases: []string{"reask"},
+				help:    "Asks the sub question instance of an answer (by number) again.",
+				executor: func(words []string) bool {
+					reask(ui, state, words)
+					return false
+				},
+			},
 			{
 				aliases: []string{"done"},
 				help:    "Exits the debugger.",
@@ -128,23 +247,41 @@ func debug(ui *userInterface, state *studentState, words []string) bool {
 			},
 			{
 				aliases: []string{"train"},
-				help:    "Runs trainhmm.",
+				help:    "Runs the student model.",
 				executor: func([]string) bool {
 					if len(state.answers) == 0 {
 						ui.println("Nothing to train.")
 						return false
 					}
-					td, err := state.writeTrainhmmInput()
-					ui.println("Temporary directory: td=%s; err=%v", td, err)
+					start := time.Now()
+					scores, err := state.model.predict()
+					ui.println("Training (%s): took %v; err=%v", state.model.getName(), time.Since(start), err)
+					for concept, skillLevel := range scores {
+						ui.println("%-20s: %f", concept.shortName, skillLevel)
+					}
+					return false
+				},
+			},
+			{
+				aliases: []string{"params"},
+				help:    "Shows the effective BKT parameters of all concepts.",
+				executor: func([]string) bool {
+					displayParams(ui)
+					return false
+				},
+			},
+			{
+				aliases: []string{"model"},
+				help:    "Shows the (serialized) state of the student model.",
+				executor: func([]string) bool {
+					data, err := json.MarshalIndent(state.model, "", "  ")
 					if err != nil {
+						ui.error("error: %s", err)
 						return false
 					}
-					err = state.runTrainhmm(td)
-					ui.println("Training: err=%v", err)
-					err = state.readPrediction(td)
-					ui.println("Reading predictions: err=%v", err)
-					for concept, skillLevel := range state.scores {
-						ui.println("%-20s: %f", concept.shortName, skillLevel)
+					ui.println("Student model: %s", state.model.getName())
+					for _, line := range strings.Split(string(data), "\n") {
+						ui.println("%s", line)
 					}
 					return false
 				},
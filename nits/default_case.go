@@ -8,6 +8,7 @@ func DefaultCase() *Case {
 	demi := &Person{Name: "Demi"}
 	bruce := &Person{Name: "Bruce"}
 	rooke := &Person{Name: "Rooke"}
+	mayko := &Person{Name: "Mayko"}
 
 	doAGoodOilChange := &Duty{
 		Description: "Perform a good quality oil change",
@@ -28,10 +29,46 @@ func DefaultCase() *Case {
 	rookesInjury := &BodilyInjury{
 		Description: "Rooke suffers serious injuries because of being thrown from the car",
 		Persons:     []*Person{rooke},
+		Amounts: []*DamageAmount{
+			{Category: Economic, Description: "Medical bills", Dollars: 180000},
+			{Category: NonEconomic, Description: "Pain and suffering", Dollars: 70000},
+			{Category: Punitive, Description: "Punitive damages for driving drunk", Dollars: 50000},
+			{Category: CollateralSource, Description: "Rooke's health insurance paid part of the medical bills", Dollars: 120000},
+		},
+		// Rooke did not wear a seatbelt.
+		Fault: []*FaultShare{
+			{Person: bruce, Percentage: 50},
+			{Person: rooke, Percentage: 20},
+			{Person: demi, Percentage: 20},
+			{Person: ashton, Percentage: 10},
+		},
 	}
 	brucesDamage := &PropertyDamage{
 		Description: "Bruce's car is seriously damaged because of the accident",
 		Persons:     []*Person{bruce},
+		Amounts: []*DamageAmount{
+			{Category: Economic, Description: "Repairs to the car", Dollars: 12000},
+			{Category: CollateralSource, Description: "Bruce's car insurance paid for the repairs", Dollars: 9000},
+		},
+		// Bruce was drunk and did not keep his distance.
+		Fault: []*FaultShare{
+			{Person: bruce, Percentage: 60},
+			{Person: demi, Percentage: 30},
+			{Person: ashton, Percentage: 10},
+		},
+	}
+
+	ashtonsEngine := &PropertyDamage{
+		Description: "The engine of Ashton's car is ruined because it ran without oil",
+		Persons:     []*Person{ashton},
+		Amounts: []*DamageAmount{
+			{Category: Economic, Description: "A new engine", Dollars: 6000},
+		},
+		// Ashton kept driving after the oil light went on.
+		Fault: []*FaultShare{
+			{Person: demi, Percentage: 80},
+			{Person: ashton, Percentage: 20},
+		},
 	}
 
 	rookeGetsThrownFromTheCar := &PassiveEvent{
@@ -43,6 +80,9 @@ func DefaultCase() *Case {
 		Description:  "Rooke did not wear a seatbelt",
 		Persons:      []*Person{rooke},
 		Consequences: []Event{rookeGetsThrownFromTheCar},
+		ProtectedClass:   "the occupants of a car",
+		ProtectedPersons: []*Person{rooke},
+		HarmTypes:        []HarmType{BodilyHarm},
 		Explanation: &Explanation{
 			Text: []string{
 				"Explanation here",
@@ -53,18 +93,31 @@ func DefaultCase() *Case {
 	rookeGetsThrownFromTheCar.NegPerSe = rookeShouldHaveWornASeatbelt
 
 	brucesCarPlowsIntoAshtonsCar := &Act{
-		shortName:         "plows",
+		ID:                "plows",
 		Person:            bruce,
 		Description:       "Bruce plows his car into Ashton's car",
 		Consequences:      []Event{rookeGetsThrownFromTheCar},
 		InjuriesOrDamages: []InjuryOrDamage{brucesDamage},
+		// A car stalled in traffic is likely to be hit, even by a driver
+		// who has had too much to drink.
+		Foreseeability: Intervening,
+		Defences: []*Defence{
+			{
+				Kind:        AssumptionOfRisk,
+				Description: "Rooke rode along with Bruce, knowing that they had been drinking together all afternoon",
+				RaisedBy:    []*Person{bruce},
+				Against:     []*Person{rooke},
+			},
+		},
 		Claims: []*Claim{
 			{
 				Person:      bruce,
 				Description: "Bruce claims that he did not see Ashton's car because of the truck in front of him",
 				Explanation: &Explanation{
 					Text: []string{
-						"Explanation of why this is irrelevant",
+						"A driver has to keep enough distance to the vehicle in front to be able to react to " +
+							"whatever happens ahead. That the truck blocked Bruce's view is no excuse; it is " +
+							"exactly the reason he should have been more careful.",
 					},
 				},
 			},
@@ -75,6 +128,9 @@ func DefaultCase() *Case {
 		Description:  "Bruce had drank too much and had blood alcohol levels over the legal limit",
 		Persons:      []*Person{bruce},
 		Consequences: []Event{brucesCarPlowsIntoAshtonsCar},
+		ProtectedClass:   "other road users and the passengers of the driver",
+		ProtectedPersons: []*Person{ashton, rooke},
+		HarmTypes:        []HarmType{BodilyHarm, PropertyHarm},
 		Explanation: &Explanation{
 			Text: []string{
 				"Explanation here",
@@ -95,9 +151,10 @@ func DefaultCase() *Case {
 		Consequences: []Event{brucesCarPlowsIntoAshtonsCar},
 	}
 	carDies := &PassiveEvent{
-		shortName:    "car_dies",
+		ID:           "car_dies",
 		Description:  "The engine of Ashton's car dies",
 		Consequences: []Event{ashtonFleesTheCar, ashtonDials911},
+		InjuriesOrDamages: []InjuryOrDamage{ashtonsEngine},
 	}
 	smokeUnderHood := &PassiveEvent{
 		Description: "Smoke comes out from under the hood of Ashton's car",
@@ -127,7 +184,8 @@ func DefaultCase() *Case {
 		Consequences: []Event{oilLightGoesOn},
 	}
 	badOilChange := &Act{
-		Description:  "Demi (or Mayko) performs a bad oil change on Ashton's car",
+		Person:       demi,
+		Description:  "Demi performs a bad oil change on Ashton's car at Mayko",
 		Consequences: []Event{lowOilPressure},
 		Duty:         doAGoodOilChange,
 	}
@@ -168,5 +226,17 @@ func DefaultCase() *Case {
 		},
 		ShortName:  "case_ashton_car_crash",
 		RootEvents: []Event{badOilChange},
+		Relationships: []*Relationship{
+			{
+				Kind:        Agency,
+				Superior:    mayko,
+				Subordinate: demi,
+				Description: "Demi serviced Ashton's car and advised him as the agent of Mayko, the garage she owns",
+			},
+		},
+		Jurisdiction: &Jurisdiction{
+			Name: "Texas",
+			Rule: ModifiedComparative51,
+		},
 	}
 }
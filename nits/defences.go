@@ -0,0 +1,305 @@
+package nits
+
+// This file implements the defences sub question. It asks which defences a
+// defendant can raise against a plaintiff in a case.
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type defencesSubQuestion struct{}
+
+func (d *defencesSubQuestion) getTag() string {
+	return "defences"
+}
+
+func (d *defencesSubQuestion) getConcepts() []*Concept {
+	return []*Concept{AssumptionOfRisk1, ContributoryNegligence1, StatuteOfLimitations1}
+}
+
+func (d *defencesSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.defencePairs()) > 0
+}
+
+var _ = addSubQuestion(&defencesSubQuestion{})
+
+// contributoryBars returns if contributory negligence is a complete
+// defence in the jurisdiction of a case. Under comparative negligence the
+// fault of the plaintiff only reduces the damages. The second return value
+// is false if the case has no jurisdiction, in which case the authored
+// defences are taken as they are.
+func (p *preprocessedCase) contributoryBars() (bool, bool) {
+	if p.jurisdiction == nil {
+		return false, false
+	}
+	return p.jurisdiction.Rule == Contributory, true
+}
+
+// damagesFrom returns the damages of a plaintiff that an event led to.
+func (p *preprocessedCase) damagesFrom(event Event, plaintiff *Person) []InjuryOrDamage {
+	result := make([]InjuryOrDamage, 0)
+	for dam := range plaintiff.damages {
+		if isCauseInFact(dam, event) {
+			result = append(result, dam)
+		}
+	}
+	p.sortDamages(result)
+	return result
+}
+
+// atFault returns if a person is at fault for a damage. A damage without
+// fault shares does not say, so then everybody might be.
+func atFault(dam InjuryOrDamage, person *Person) bool {
+	return len(dam.getFault()) == 0 || faultShare(dam, person) > 0
+}
+
+// defenceApplies checks an authored defence against the case graph: the
+// event of the defence must have led to a damage of the plaintiff that the
+// defendant is at fault for, the plaintiff must be at fault for it too if
+// it is contributory negligence, and contributory negligence must be a
+// complete defence in the jurisdiction of the case.
+func (p *preprocessedCase) defenceApplies(d *Defence, defendant, plaintiff *Person) bool {
+	if _, ok := personSet(d.RaisedBy)[defendant]; !ok {
+		return false
+	}
+	if _, ok := personSet(d.Against)[plaintiff]; !ok {
+		return false
+	}
+	if d.Kind == ContributoryNegligenceDefence {
+		if bars, known := p.contributoryBars(); known && !bars {
+			return false
+		}
+	}
+	for _, dam := range p.damagesFrom(d.event, plaintiff) {
+		if atFault(dam, defendant) && (d.Kind != ContributoryNegligenceDefence || atFault(dam, plaintiff)) {
+			return true
+		}
+	}
+	return false
+}
+
+// answersFor returns a defendant together with the persons the defendant
+// is vicariously liable for. A principal can raise the defences of an
+// agent, because the liability of the principal is that of the agent.
+func (p *preprocessedCase) answersFor(defendant *Person) map[*Person]interface{} {
+	result := map[*Person]interface{}{defendant: nil}
+	for person := range p.persons {
+		if _, ok := p.vicariouslyLiable(personSet([]*Person{person}))[defendant]; ok {
+			result[person] = nil
+		}
+	}
+	return result
+}
+
+// faultOfPlaintiff returns the damages of a plaintiff that the plaintiff
+// and one of a set of defendants both have a share of the fault for.
+func (p *preprocessedCase) faultOfPlaintiff(defendants map[*Person]interface{}, plaintiff *Person) []InjuryOrDamage {
+	result := make([]InjuryOrDamage, 0)
+	for dam := range plaintiff.damages {
+		if faultShare(dam, plaintiff) == 0 {
+			continue
+		}
+		for defendant := range defendants {
+			if faultShare(dam, defendant) > 0 {
+				result = append(result, dam)
+				break
+			}
+		}
+	}
+	p.sortDamages(result)
+	return result
+}
+
+// defencesBetween finds the defences a defendant can raise against a
+// plaintiff: the authored defences that the case graph supports, sorted by
+// id, and contributory negligence if the fault shares in the case show
+// that the plaintiff was negligent too and the jurisdiction follows
+// contributory negligence. A defendant who is vicariously liable for
+// someone else gets the defences of that person too.
+func (p *preprocessedCase) defencesBetween(defendant, plaintiff *Person) []*Defence {
+	persons := p.answersFor(defendant)
+	delete(persons, plaintiff)
+	result := make([]*Defence, 0)
+	contributory := false
+	for d := range p.defences {
+		for person := range persons {
+			if p.defenceApplies(d, person, plaintiff) {
+				result = append(result, d)
+				contributory = contributory || d.Kind == ContributoryNegligenceDefence
+				break
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return p.key(result[i]) < p.key(result[j])
+	})
+	if bars, _ := p.contributoryBars(); bars && !contributory {
+		for _, dam := range p.faultOfPlaintiff(persons, plaintiff) {
+			result = append(result, &Defence{
+				Kind:        ContributoryNegligenceDefence,
+				Description: fmt.Sprintf("%s is %d%% at fault for: %s", plaintiff.Name, faultShare(dam, plaintiff), dam.GetDescription()),
+				RaisedBy:    []*Person{defendant},
+				Against:     []*Person{plaintiff},
+			})
+		}
+	}
+	return result
+}
+
+// defencePair is a defendant and a plaintiff.
+type defencePair struct {
+	defendant *Person
+	plaintiff *Person
+}
+
+// defencePairs finds all the defendants and plaintiffs between whom there
+// is a defence, sorted by id.
+func (p *preprocessedCase) defencePairs() []*defencePair {
+	seen := make(map[defencePair]interface{})
+	consider := func(defendants, plaintiffs []*Person) {
+		// The principals of the defendants can raise their defences too.
+		all := union(personSet(defendants), p.vicariouslyLiable(personSet(defendants)))
+		for defendant := range all {
+			for _, plaintiff := range plaintiffs {
+				if defendant != plaintiff {
+					seen[defencePair{defendant: defendant, plaintiff: plaintiff}] = nil
+				}
+			}
+		}
+	}
+	for d := range p.defences {
+		consider(d.RaisedBy, d.Against)
+	}
+	for dam := range p.injuriesOrDamages {
+		persons := make([]*Person, 0)
+		for _, f := range dam.getFault() {
+			persons = append(persons, f.Person)
+		}
+		consider(persons, dam.GetPersons())
+	}
+	result := make([]*defencePair, 0)
+	for pair := range seen {
+		if len(p.defencesBetween(pair.defendant, pair.plaintiff)) > 0 {
+			pair := pair
+			result = append(result, &pair)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return p.key(result[i].defendant, result[i].plaintiff) < p.key(result[j].defendant, result[j].plaintiff)
+	})
+	return result
+}
+
+// getDefenceKinds gets a list of kinds of defences from the user, entered
+// as their numbers in defenceKinds, or "none".
+func getDefenceKinds(ui *userInterface) (map[DefenceKind]bool, bool) {
+	for {
+		words, ret := ui.getInput()
+		if ret {
+			return nil, ret
+		}
+		kinds := make(map[DefenceKind]bool)
+		if len(words) == 1 && words[0] == "none" {
+			return kinds, false
+		}
+		ok := true
+		for _, w := range words {
+			n, err := strconv.Atoi(w)
+			if err != nil || n < 1 || n > len(defenceKinds) {
+				ok = false
+				break
+			}
+			kinds[defenceKinds[n-1]] = true
+		}
+		if ok {
+			return kinds, false
+		}
+		ui.error("Please enter the numbers of the defences, or none.")
+	}
+}
+
+// newInstance picks a random defendant and plaintiff between whom there is
+// a defence.
+func (d *defencesSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	pairs := pp.defencePairs()
+	if len(pairs) == 0 {
+		return nil
+	}
+	pair := pairs[r.Intn(len(pairs))]
+	return pp.newInstance(d, pair.defendant, pair.plaintiff)
+}
+
+// ask asks the defences sub question.
+func (d *defencesSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	defendant, ok1 := pp.ref(inst, 0).(*Person)
+	plaintiff, ok2 := pp.ref(inst, 1).(*Person)
+	if !ok1 || !ok2 {
+		return badInstance(ui, inst)
+	}
+	applicable := pp.defencesBetween(defendant, plaintiff)
+	rightAnswer := make(map[DefenceKind]bool)
+	for _, a := range applicable {
+		rightAnswer[a.Kind] = true
+	}
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("Suppose %s sues %s. Which of these defences can %s raise?", plaintiff.Name, defendant.Name, defendant.Name)
+		ui.newline()
+		for i, k := range defenceKinds {
+			ui.println("%d. %s", i+1, k)
+		}
+		ui.newline()
+		ui.println("Enter the numbers of all the defences that apply, or none.")
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses := make([]string, 0)
+
+	for {
+		kinds, ret := getDefenceKinds(ui)
+		if ret {
+			return ret
+		}
+		names := make([]string, 0, len(kinds))
+		for _, k := range defenceKinds {
+			if kinds[k] {
+				names = append(names, k.String())
+			}
+		}
+		responses = append(responses, strings.Join(names, ", "))
+		if len(kinds) == len(rightAnswer) {
+			correct := true
+			for k := range kinds {
+				correct = correct && rightAnswer[k]
+			}
+			if correct {
+				ui.println("Correct :-)")
+				break
+			}
+		}
+		ui.println("Please try again :-(")
+	}
+	for _, a := range applicable {
+		ui.newline()
+		ui.println("%s: %s", a.Kind, a.Description)
+		if a.Explanation != nil {
+			ui.explain(a.Explanation)
+		}
+	}
+	if bars, known := pp.contributoryBars(); known && !bars && len(pp.faultOfPlaintiff(pp.answersFor(defendant), plaintiff)) > 0 {
+		ui.newline()
+		ui.println("%s is partly at fault too, but under %s that only reduces the damages; it is not a defence.", plaintiff.Name, pp.jurisdiction.Rule)
+	}
+	state.registerAnswer(c, d, responses)
+	return false
+}
@@ -0,0 +1,53 @@
+package nits
+
+import "testing"
+
+func TestDefencesBetween(t *testing.T) {
+	pp := DefaultCase().preprocess()
+	persons := make(map[string]*Person)
+	for p := range pp.persons {
+		persons[p.Name] = p
+	}
+	for _, test := range []struct {
+		defendant, plaintiff string
+		want                 int
+	}{
+		// Texas follows comparative negligence, so only the assumption
+		// of risk is a defence.
+		{"Bruce", "Rooke", 1},
+		{"Demi", "Rooke", 0},
+		{"Mayko", "Ashton", 0},
+		{"Rooke", "Bruce", 0},
+	} {
+		if got := len(pp.defencesBetween(persons[test.defendant], persons[test.plaintiff])); got != test.want {
+			t.Errorf("defencesBetween(%s, %s); got: %d defences, want: %d", test.defendant, test.plaintiff, got, test.want)
+		}
+	}
+
+	// Under contributory negligence the fault shares of the plaintiffs
+	// give the defendants who are at fault too a defence.
+	c := DefaultCase()
+	c.Jurisdiction = &Jurisdiction{Name: "Alabama", Rule: Contributory}
+	pp = c.preprocess()
+	persons = make(map[string]*Person)
+	for p := range pp.persons {
+		persons[p.Name] = p
+	}
+	for _, test := range []struct {
+		defendant, plaintiff string
+		want                 int
+	}{
+		{"Bruce", "Rooke", 2},
+		{"Demi", "Rooke", 1},
+		{"Demi", "Ashton", 1},
+		// Mayko is vicariously liable for Demi, so Mayko has the
+		// defences of Demi.
+		{"Mayko", "Rooke", 1},
+		{"Mayko", "Ashton", 1},
+		{"Rooke", "Bruce", 0},
+	} {
+		if got := len(pp.defencesBetween(persons[test.defendant], persons[test.plaintiff])); got != test.want {
+			t.Errorf("defencesBetween(%s, %s) under contributory negligence; got: %d defences, want: %d", test.defendant, test.plaintiff, got, test.want)
+		}
+	}
+}
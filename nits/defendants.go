@@ -6,7 +6,6 @@ package nits
 
 import (
 	"math/rand"
-	"sort"
 	"strings"
 )
 
@@ -20,115 +19,67 @@ func (p *defendantsSubQuestion) getConcepts() []*Concept {
 	return []*Concept{Defendant0}
 }
 
+// applies tells if the sub question can be asked about a case: there must
+// be a damage that was caused by a breached duty.
+func (p *defendantsSubQuestion) applies(pp *preprocessedCase) bool {
+	for dam := range pp.injuriesOrDamages {
+		if len(findDuties(dam)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 var _ = addSubQuestion(&defendantsSubQuestion{})
 
-// Asks the defendants sub question.
-func (p *defendantsSubQuestion) ask(c *Case, ui *userInterface, state *studentState) bool {
-	// Collects all the damages in a case into a slice and randomly shuffles
-	// them.
-  pp := c.preprocess()
+// newInstance picks a random damage that was caused by a breached duty.
+func (p *defendantsSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
 	dams := make([]InjuryOrDamage, 0, len(pp.injuriesOrDamages))
-	for dam, _ := range pp.injuriesOrDamages {
-		dams = append(dams, dam)
-	}
-	rand.Shuffle(len(dams), func(i,j int) {
-		dams[i], dams[j] = dams[j], dams[i]
-	})
-	// For all the damages, find if there where breached duties in the
-	// causality chain.
-	for _, dam := range dams {
-		duties := findDuties(dam)
-		// If there are none, try the next damage.
-		if len(duties) == 0 {
-			continue
+	for dam := range pp.injuriesOrDamages {
+		if len(findDuties(dam)) > 0 {
+			dams = append(dams, dam)
 		}
-		// We have found one or more breached duties that led to this damage.
-		// Ask the student the names of all the people who had this duty.
-		attempts := 0
-		for {
-			ui.newline()
-			ui.println("Consider the following damage:")
-			ui.println(dam.GetDescription())
-			ui.println("Please enter the names of all people who could be held responsible for this:")
-			ui.println("(Enter one name per line, finish with a . on a line of its own)")
-			names := make([]string, 0)
-
-			for {
-				words, ret := ui.getInput()
-				if ret {
-					return ret
-				}
-				if len(words) == 0 {
-					continue
-				}
-				if len(words) > 1 {
-					ui.println("Please enter one word names only, finish with a . on a line of its own")
-					continue
-				}
-				if words[0] == "." {
-					break
-				}
-				names = append(names, words[0])
-			}
-
-			ui.println("You entered:")
-			for _, name := range names {
-				ui.println("- %s", name)
-			}
-
-			yes, ret := ui.yesNo("Is this correct")
-			if ret {
-				return ret
-			}
-			if !yes {
-				ui.println("Ok, try again")
-				continue
-			}
-
-			// Compares the answer of the student with all the persons
-			// collected from the breached duties that led to this
-			// damage.
-
-			// names is the list entered by the student. Sort this list
-			// by name.
-			sort.Slice(names, func(i, j int) bool {
-				return names[i] < names[j]
-			})
-
-			// Collect the persons from the duties, put their names
-			// (lower case) in a slice, and sort that slice by name.
-			names2 := make([]string, 0)
-			for p, _ := range collectPersonsFromDuties(duties) {
-				names2 = append(names2, strings.ToLower(p.Name))
-			}
-
-			sort.Slice(names2, func(i, j int) bool {
-				return names2[i] < names2[j]
-			})
-
-			// Now compare the two slices.
-			if func() bool {
-				if len(names) != len(names2) {
-					return false
-				}
-
-				for i := range names {
-					if names[i] != names2[i] {
-						return false
-					}
-				}
-
-				return true
-			}() {
-				ui.println("Correct!")
-				state.registerAnswer(c, p, attempts == 0)
-				return false
-			}
+	}
+	if len(dams) == 0 {
+		return nil
+	}
+	pp.sortDamages(dams)
+	return pp.newInstance(p, dams[r.Intn(len(dams))])
+}
 
-			ui.println("Incorrect :-(")
-			attempts++
-		}
+// Asks the defendants sub question.
+func (p *defendantsSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	dam, ok := pp.ref(inst, 0).(InjuryOrDamage)
+	if !ok {
+		return badInstance(ui, inst)
 	}
+	// There are one or more breached duties that led to this damage. Ask
+	// the student the names of all the people who had this duty.
+	duties := findDuties(dam)
+	responses := make([]string, 0)
+	for {
+		ui.newline()
+		ui.println("Consider the following damage:")
+		ui.println(dam.GetDescription())
+		ui.println("Please enter the names of all people who could be held responsible for this:")
+		names, ret := ui.getNames()
+		if ret {
+			return ret
+		}
+		responses = append(responses, strings.Join(names, ", "))
+
+		// Compares the answer of the student with all the persons
+		// collected from the breached duties that led to this
+		// damage. Students who also name the persons that are
+		// vicariously liable are right as well.
+		direct := collectPersonsFromDuties(duties)
+		if namesMatch(names, direct) || namesMatch(names, union(direct, pp.vicariouslyLiable(direct))) {
+			ui.println("Correct!")
+			state.registerAnswer(c, p, responses)
+			return false
+		}
 
-	return false
+		ui.println("Incorrect :-(")
+	}
 }
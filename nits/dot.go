@@ -14,6 +14,7 @@ import (
 const (
 	shapePerson         = "diamond"
 	shapeClaim          = "trapezium"
+	shapeDefence        = "octagon"
 	shapeEvent          = "ellipse"
 	shapeAct            = "box"
 	shapeDuty           = "hexagon"
@@ -84,19 +85,25 @@ func makeCaseDot(state *studentState, words []string) (string, error) {
 	if err != nil {
 		return f.Name(), err
 	}
-	if err := dotPersons(f, pp.persons); err != nil {
+	if err := pp.dotPersons(f, pp.persons); err != nil {
 		return "", err
 	}
-	if err := dotClaims(f, pp.claims); err != nil {
+	if err := pp.dotClaims(f, pp.claims); err != nil {
 		return "", err
 	}
-	if err := dotDuties(f, pp.duties); err != nil {
+	if err := pp.dotDefences(f, pp.defences); err != nil {
 		return "", err
 	}
-	if err := dotInjuryOrDamages(f, pp.injuriesOrDamages); err != nil {
+	if err := pp.dotRelationships(f, pp.relationships); err != nil {
 		return "", err
 	}
-	if err := dotEvents(f, pp.events); err != nil {
+	if err := pp.dotDuties(f, pp.duties); err != nil {
+		return "", err
+	}
+	if err := pp.dotInjuryOrDamages(f, pp.injuriesOrDamages); err != nil {
+		return "", err
+	}
+	if err := pp.dotEvents(f, pp.events); err != nil {
 		return "", err
 	}
 	_, err = f.WriteString("}\n")
@@ -105,24 +112,31 @@ func makeCaseDot(state *studentState, words []string) (string, error) {
 }
 
 // hasLabel is an interface for something that has a label. Every object that
-// needs to be drawn needs to have one.
+// needs to be drawn needs to have one, and an id.
 type hasLabel interface {
+	identifiable
 	getLabel() string
 }
 
+// node returns the name of the dot node for an object. Nodes are named by
+// the ids of the objects, so that they are the same on every run.
+func (p *preprocessedCase) node(t string, obj identifiable) string {
+	return fmt.Sprintf("%q", t+"_"+p.id(obj))
+}
+
 // draw generates a dot shape for an object.
-func draw(f *os.File, t string, shape string, obj hasLabel) error {
-	_, err := f.WriteString(fmt.Sprintf("  %s_%p [shape=%s,label=\"%s\"];\n", t, obj, shape, obj.getLabel()))
+func (p *preprocessedCase) draw(f *os.File, t string, shape string, obj hasLabel) error {
+	_, err := f.WriteString(fmt.Sprintf("  %s [shape=%s,label=\"%s\"];\n", p.node(t, obj), shape, obj.getLabel()))
 	return err
 }
 
-func dotInjuryOrDamages(f *os.File, id map[InjuryOrDamage]interface{}) error {
+func (p *preprocessedCase) dotInjuryOrDamages(f *os.File, id map[InjuryOrDamage]interface{}) error {
 	for dam := range id {
-		if err := draw(f, "dam", shapeInjuryOrDamage, dam); err != nil {
+		if err := p.draw(f, "dam", shapeInjuryOrDamage, dam); err != nil {
 			return err
 		}
 		for _, person := range dam.GetPersons() {
-			if _, err := f.WriteString(fmt.Sprintf("  person_%p -> dam_%p [style=dotted];\n", person, dam)); err != nil {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s [style=dotted];\n", p.node("person", person), p.node("dam", dam))); err != nil {
 				return nil
 			}
 		}
@@ -130,18 +144,18 @@ func dotInjuryOrDamages(f *os.File, id map[InjuryOrDamage]interface{}) error {
 	return nil
 }
 
-func dotDuties(f *os.File, duties map[*Duty]interface{}) error {
+func (p *preprocessedCase) dotDuties(f *os.File, duties map[*Duty]interface{}) error {
 	for duty := range duties {
-		if err := draw(f, "duty", shapeDuty, duty); err != nil {
+		if err := p.draw(f, "duty", shapeDuty, duty); err != nil {
 			return err
 		}
 		for _, person := range duty.OwedFrom {
-			if _, err := f.WriteString(fmt.Sprintf("  person_%p -> duty_%p;\n", person, duty)); err != nil {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s;\n", p.node("person", person), p.node("duty", duty))); err != nil {
 				return nil
 			}
 		}
 		for _, person := range duty.OwedTo {
-			if _, err := f.WriteString(fmt.Sprintf("  duty_%p -> person_%p;\n", duty, person)); err != nil {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s;\n", p.node("duty", duty), p.node("person", person))); err != nil {
 				return nil
 			}
 		}
@@ -149,21 +163,52 @@ func dotDuties(f *os.File, duties map[*Duty]interface{}) error {
 	return nil
 }
 
-func dotPersons(f *os.File, persons map[*Person]interface{}) error {
+func (p *preprocessedCase) dotPersons(f *os.File, persons map[*Person]interface{}) error {
 	for person := range persons {
-		if err := draw(f, "person", shapePerson, person); err != nil {
+		if err := p.draw(f, "person", shapePerson, person); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *preprocessedCase) dotDefences(f *os.File, defences map[*Defence]interface{}) error {
+	for defence := range defences {
+		if err := p.draw(f, "defence", shapeDefence, defence); err != nil {
+			return err
+		}
+		if _, err := f.WriteString(fmt.Sprintf("  %s -> %s [style=dotted];\n", p.node("defence", defence), p.node("event", defence.event))); err != nil {
+			return err
+		}
+		for _, person := range defence.RaisedBy {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s;\n", p.node("person", person), p.node("defence", defence))); err != nil {
+				return err
+			}
+		}
+		for _, person := range defence.Against {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s;\n", p.node("defence", defence), p.node("person", person))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *preprocessedCase) dotRelationships(f *os.File, relationships []*Relationship) error {
+	for _, r := range relationships {
+		if _, err := f.WriteString(fmt.Sprintf("  %s -> %s [style=dashed,label=\"%s\"];\n", p.node("person", r.Superior), p.node("person", r.Subordinate), r.Kind)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func dotClaims(f *os.File, claims map[*Claim]interface{}) error {
+func (p *preprocessedCase) dotClaims(f *os.File, claims map[*Claim]interface{}) error {
 	for claim := range claims {
-		if err := draw(f, "claim", shapeClaim, claim); err != nil {
+		if err := p.draw(f, "claim", shapeClaim, claim); err != nil {
 			return err
 		}
-		if _, err := f.WriteString(fmt.Sprintf("  person_%p -> claim_%p [style=dotted];\n", claim.Person, claim)); err != nil {
+		if _, err := f.WriteString(fmt.Sprintf("  %s -> %s [style=dotted];\n", p.node("person", claim.Person), p.node("claim", claim))); err != nil {
 			return err
 		}
 	}
@@ -177,46 +222,46 @@ func getEventShape(e Event) string {
 	return shapeEvent
 }
 
-func dotEvents(f *os.File, events map[Event]interface{}) error {
+func (p *preprocessedCase) dotEvents(f *os.File, events map[Event]interface{}) error {
 	for event := range events {
-		if err := draw(f, "event", getEventShape(event), event); err != nil {
+		if err := p.draw(f, "event", getEventShape(event), event); err != nil {
 			return err
 		}
 	}
 
 	for event := range events {
 		for _, consequence := range event.getConsequences() {
-			if _, err := f.WriteString(fmt.Sprintf("  event_%p -> event_%p;\n", event, consequence)); err != nil {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s;\n", p.node("event", event), p.node("event", consequence))); err != nil {
 				return err
 			}
 		}
 		if event.getDuty() != nil {
-			if _, err := f.WriteString(fmt.Sprintf("  duty_%p -> event_%p [style=dotted];\n", event.getDuty(), event)); err != nil {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s [style=dotted];\n", p.node("duty", event.getDuty()), p.node("event", event))); err != nil {
 				return err
 			}
 		}
 		if event.getClaims() != nil {
 			for _, claim := range event.getClaims() {
-				if _, err := f.WriteString(fmt.Sprintf("  claim_%p -> event_%p [style=dotted];\n", claim, event)); err != nil {
+				if _, err := f.WriteString(fmt.Sprintf("  %s -> %s [style=dotted];\n", p.node("claim", claim), p.node("event", event))); err != nil {
 					return err
 				}
 			}
 		}
 		if event.getNegPerSe() != nil {
-			if err := draw(f, "negperse", shapeNegPerSe, event.getNegPerSe()); err != nil {
+			if err := p.draw(f, "negperse", shapeNegPerSe, event.getNegPerSe()); err != nil {
 				return err
 			}
-			if _, err := f.WriteString(fmt.Sprintf("  negperse_%p -> event_%p [style=dotted];\n", event.getNegPerSe(), event)); err != nil {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s [style=dotted];\n", p.node("negperse", event.getNegPerSe()), p.node("event", event))); err != nil {
 				return err
 			}
 			for _, person := range event.getNegPerSe().Persons {
-				if _, err := f.WriteString(fmt.Sprintf("  person_%p -> negperse_%p [style=dotted];\n", person, event.getNegPerSe())); err != nil {
+				if _, err := f.WriteString(fmt.Sprintf("  %s -> %s [style=dotted];\n", p.node("person", person), p.node("negperse", event.getNegPerSe()))); err != nil {
 					return err
 				}
 			}
 		}
 		for _, dam := range event.getInjuriesOrDamages() {
-			if _, err := f.WriteString(fmt.Sprintf("  event_%p -> dam_%p;\n", event, dam)); err != nil {
+			if _, err := f.WriteString(fmt.Sprintf("  %s -> %s;\n", p.node("event", event), p.node("dam", dam))); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,205 @@
+package nits
+
+// This file implements the duty and breach sub questions. The duty sub
+// question asks who owed a duty to whom, the breach sub question asks
+// which event breached a duty. Both are graded against the case graph.
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// maxBreachChoices is the maximum number of events the student can choose
+// from in the breach sub question.
+const maxBreachChoices = 4
+
+// sortedDuties returns all the duties in a case, sorted by id.
+func (p *preprocessedCase) sortedDuties() []*Duty {
+	duties := make([]*Duty, 0, len(p.duties))
+	for d := range p.duties {
+		duties = append(duties, d)
+	}
+	sort.Slice(duties, func(i, j int) bool {
+		return p.key(duties[i]) < p.key(duties[j])
+	})
+	return duties
+}
+
+// randomDuty finds a random duty in a case, or nil if there are none.
+func (p *preprocessedCase) randomDuty(r *rand.Rand) *Duty {
+	duties := p.sortedDuties()
+	if len(duties) == 0 {
+		return nil
+	}
+	return duties[r.Intn(len(duties))]
+}
+
+// breachChoices returns the choices for the event that breached a duty:
+// the event itself and a few other events in the case, shuffled.
+func (p *preprocessedCase) breachChoices(duty *Duty, r *rand.Rand) []*Answer {
+	others := make([]Event, 0, len(p.events))
+	for e := range p.events {
+		// Other events with the same duty breach it too, so they can not
+		// be wrong answers.
+		if e.getDuty() != duty {
+			others = append(others, e)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return p.key(others[i]) < p.key(others[j])
+	})
+	r.Shuffle(len(others), func(i, j int) {
+		others[i], others[j] = others[j], others[i]
+	})
+	if len(others) > maxBreachChoices-1 {
+		others = others[:maxBreachChoices-1]
+	}
+	answers := []*Answer{{Text: duty.event.getDescription(), Correct: true}}
+	for _, e := range others {
+		answers = append(answers, &Answer{Text: e.getDescription()})
+	}
+	r.Shuffle(len(answers), func(i, j int) {
+		answers[i], answers[j] = answers[j], answers[i]
+	})
+	return answers
+}
+
+// --------------------------------------------------------------------
+
+type dutySubQuestion struct{}
+
+func (d *dutySubQuestion) getTag() string {
+	return "duty"
+}
+
+func (d *dutySubQuestion) getConcepts() []*Concept {
+	return []*Concept{Duty1}
+}
+
+func (d *dutySubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.duties) > 0
+}
+
+var _ = addSubQuestion(&dutySubQuestion{})
+
+// newInstance picks a random duty.
+func (d *dutySubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	duty := pp.randomDuty(r)
+	if duty == nil {
+		return nil
+	}
+	return pp.newInstance(d, duty)
+}
+
+// ask asks the duty sub question.
+func (d *dutySubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	duty, ok := c.preprocess().ref(inst, 0).(*Duty)
+	if !ok {
+		return badInstance(ui, inst)
+	}
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("In this case there is the following duty:")
+		ui.println(duty.Description)
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses := make([]string, 0)
+
+	for {
+		ui.println("Please enter the names of all people who owed this duty:")
+		from, ret := ui.getNames()
+		if ret {
+			return ret
+		}
+		ui.println("Please enter the names of all people this duty was owed to:")
+		to, ret := ui.getNames()
+		if ret {
+			return ret
+		}
+		responses = append(responses, fmt.Sprintf("from: %s; to: %s", strings.Join(from, ", "), strings.Join(to, ", ")))
+
+		fromOK := namesMatch(from, personSet(duty.OwedFrom))
+		toOK := namesMatch(to, personSet(duty.OwedTo))
+		if fromOK && toOK {
+			ui.println("Correct!")
+			state.registerAnswer(c, d, responses)
+			return false
+		}
+		if !fromOK {
+			ui.println("The people who owed the duty are not correct :-(")
+		}
+		if !toOK {
+			ui.println("The people the duty was owed to are not correct :-(")
+		}
+	}
+}
+
+// --------------------------------------------------------------------
+
+type breachSubQuestion struct{}
+
+func (b *breachSubQuestion) getTag() string {
+	return "breach"
+}
+
+func (b *breachSubQuestion) getConcepts() []*Concept {
+	return []*Concept{Breach1}
+}
+
+func (b *breachSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.duties) > 0
+}
+
+var _ = addSubQuestion(&breachSubQuestion{})
+
+// newInstance picks a random duty.
+func (b *breachSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	duty := pp.randomDuty(r)
+	if duty == nil {
+		return nil
+	}
+	return pp.newInstance(b, duty)
+}
+
+// ask asks the breach sub question. The student picks the event that
+// breached a duty from a few events in the case.
+func (b *breachSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	duty, ok := pp.ref(inst, 0).(*Duty)
+	if !ok || duty.event == nil {
+		return badInstance(ui, inst)
+	}
+
+	answers := pp.breachChoices(duty, state.rng)
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("In this case there is the following duty:")
+		ui.println(duty.Description)
+		ui.println("Which of these events breached that duty?")
+		ui.newline()
+		ui.printAnswers(answers)
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses, ret := askChoice(ui, answers)
+	if ret {
+		return ret
+	}
+	state.registerAnswer(c, b, responses)
+	return false
+}
@@ -0,0 +1,27 @@
+package nits
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBreachChoicesSharedDuty(t *testing.T) {
+	ann := &Person{Name: "Ann"}
+	bob := &Person{Name: "Bob"}
+	duty := &Duty{Description: "Keep the dog on a leash", OwedFrom: []*Person{ann}, OwedTo: []*Person{bob}}
+	bite := &BodilyInjury{Description: "Bob is bitten", Persons: []*Person{bob}}
+	bites := &PassiveEvent{Description: "The dog bites Bob", InjuriesOrDamages: []InjuryOrDamage{bite}}
+	lets := &Act{Description: "Ann lets the dog off the leash", Person: ann, Duty: duty, Consequences: []Event{bites}}
+	drops := &Act{Description: "Ann drops the leash", Person: ann, Duty: duty, Consequences: []Event{lets}}
+	walks := &Act{Description: "Ann walks the dog", Person: ann, Consequences: []Event{drops}}
+	c := &Case{ShortName: "case_dog", RootEvents: []Event{walks}}
+
+	pp := c.preprocess()
+	for seed := int64(0); seed < 10; seed++ {
+		for _, a := range pp.breachChoices(duty, rand.New(rand.NewSource(seed))) {
+			if !a.Correct && (a.Text == lets.Description || a.Text == drops.Description) {
+				t.Fatalf("breachChoices(); got: %q as a wrong answer, but it breaches the duty too", a.Text)
+			}
+		}
+	}
+}
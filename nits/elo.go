@@ -0,0 +1,130 @@
+package nits
+
+// This file contains an Elo style student model. Every concept has a
+// rating for the student and every question (or sub question) has a
+// difficulty rating. After every answer both ratings move towards the
+// outcome, by an amount that decreases as more answers are seen.
+
+import (
+	"encoding/json"
+	"math"
+)
+
+const (
+	eloK     = 1.0  // Initial step size for rating updates.
+	eloDecay = 0.05 // How fast the step size decreases with the number of answers.
+)
+
+// eloModel is the Elo student model.
+type eloModel struct {
+	ratings      map[*Concept]float64 // Student rating per concept.
+	attempts     map[*Concept]int     // Number of answers seen per concept.
+	difficulties map[string]float64   // Difficulty per question tag.
+	seen         map[string]int       // Number of answers seen per question tag.
+}
+
+// sigmoid is the logistic function.
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// answerTag returns a tag that identifies the question (and the sub
+// question, if any) of an answer.
+func answerTag(a *answer) string {
+	if a.subQuestion == nil {
+		return a.questionShortName
+	}
+	return a.questionShortName + "#" + a.subQuestion.getTag()
+}
+
+// eloStep returns the step size after n answers.
+func eloStep(n int) float64 {
+	return eloK / (1 + eloDecay*float64(n))
+}
+
+func (m *eloModel) getName() string {
+	return "elo"
+}
+
+func (m *eloModel) reset() {
+	m.ratings = make(map[*Concept]float64)
+	m.attempts = make(map[*Concept]int)
+	m.difficulties = make(map[string]float64)
+	m.seen = make(map[string]int)
+}
+
+// update moves the ratings of the concepts and the difficulty of the
+// question towards the outcome of the answer.
+func (m *eloModel) update(a *answer) {
+	if a.question == nil {
+		return
+	}
+	concepts := a.question.getTrainingConcepts(a.subQuestion)
+	if len(concepts) == 0 {
+		return
+	}
+	tag := answerTag(a)
+	// The expected outcome uses the average rating of the concepts
+	// involved.
+	total := 0.0
+	for _, c := range concepts {
+		total += m.ratings[c]
+	}
+	expected := sigmoid(total/float64(len(concepts)) - m.difficulties[tag])
+	outcome := 0.0
+	if a.correct {
+		outcome = 1.0
+	}
+	for _, c := range concepts {
+		m.ratings[c] += eloStep(m.attempts[c]) * (outcome - expected)
+		m.attempts[c]++
+	}
+	m.difficulties[tag] -= eloStep(m.seen[tag]) * (outcome - expected)
+	m.seen[tag]++
+}
+
+// predict returns the probability that the student answers a question of
+// average difficulty correctly, per concept.
+func (m *eloModel) predict() (map[*Concept]float64, error) {
+	scores := make(map[*Concept]float64, len(m.ratings))
+	for c, r := range m.ratings {
+		scores[c] = sigmoid(r)
+	}
+	return scores, nil
+}
+
+// eloState is the JSON representation of the Elo model.
+type eloState struct {
+	Model        string             `json:"model"`
+	Ratings      conceptScores      `json:"ratings"`
+	Attempts     map[string]int     `json:"attempts"`
+	Difficulties map[string]float64 `json:"difficulties"`
+	Seen         map[string]int     `json:"seen"`
+}
+
+func (m *eloModel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&eloState{
+		Model:        m.getName(),
+		Ratings:      toConceptScores(m.ratings),
+		Attempts:     countsByName(m.attempts),
+		Difficulties: m.difficulties,
+		Seen:         m.seen,
+	})
+}
+
+func (m *eloModel) UnmarshalJSON(b []byte) error {
+	var v eloState
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	m.reset()
+	m.ratings = v.Ratings.fromConceptScores()
+	m.attempts = countsByConcept(v.Attempts)
+	for tag, d := range v.Difficulties {
+		m.difficulties[tag] = d
+	}
+	for tag, n := range v.Seen {
+		m.seen[tag] = n
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package nits
+
+// This file contains the offline fitting of BKT parameters on the answer
+// logs of many students, and the loading of the resulting parameter file.
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+)
+
+var paramsFlag = flag.String("params", "", "File with fitted BKT parameters (see: nits fit)")
+
+// fittedParams are the fitted BKT parameters for a single concept, as
+// stored in a parameter file.
+type fittedParams struct {
+	PInit         float64 `json:"pInit"`
+	PLearn        float64 `json:"pLearn"`
+	PSlip         float64 `json:"pSlip"`
+	PGuess        float64 `json:"pGuess"`
+	Students      int     `json:"students"`
+	Observations  int     `json:"observations"`
+	LogLikelihood float64 `json:"logLikelihood"`
+}
+
+// paramsFile is the content of a parameter file.
+type paramsFile struct {
+	Concepts map[string]*fittedParams `json:"concepts"`
+}
+
+// readAnswerLogs reads the student data in a directory of student
+// profiles, laid out like ~/.nits/profiles: every profile is a directory
+// with the answers of one student in its data file. Backups of the data
+// are not read, as they would count the same student again.
+func (c *Content) readAnswerLogs(dir string) ([][]*answer, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	students := make([][]*answer, 0, len(files))
+	for _, fi := range files {
+		if !fi.IsDir() {
+			continue
+		}
+		fname := path.Join(dir, fi.Name(), "data")
+		data, err := ioutil.ReadFile(fname)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ud, err := c.parseUserData(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", fname, err)
+			continue
+		}
+		students = append(students, ud.Answers)
+	}
+	return students, nil
+}
+
+// fitParams fits the BKT parameters per concept over the answers of
+// many students. Every student contributes one observation sequence per
+// concept.
+func fitParams(students [][]*answer) map[*Concept]*fittedParams {
+	seqs := make(map[*Concept][][]bool)
+	for _, answers := range students {
+		for c, obs := range observations(answers) {
+			seqs[c] = append(seqs[c], obs)
+		}
+	}
+	result := make(map[*Concept]*fittedParams, len(seqs))
+	for c, s := range seqs {
+		p, ll := c.getBKTParams().fit(s)
+		n := 0
+		for _, obs := range s {
+			n += len(obs)
+		}
+		result[c] = &fittedParams{
+			PInit:         p.pInit,
+			PLearn:        p.pLearn,
+			PSlip:         p.pSlip,
+			PGuess:        p.pGuess,
+			Students:      len(s),
+			Observations:  n,
+			LogLikelihood: ll,
+		}
+	}
+	return result
+}
+
+// Fit implements the fit command: it fits the BKT parameters of all
+// concepts on a directory with student profiles and writes them to a
+// parameter file that can be loaded with -params.
+func Fit(content *Content, args []string) error {
+	fs := flag.NewFlagSet("fit", flag.ExitOnError)
+	logs := fs.String("logs", "", "Directory with the student profiles to fit on (like ~/.nits/profiles)")
+	out := fs.String("out", "nits_params.json", "Parameter file to write")
+	fs.Parse(args)
+	if *logs == "" {
+		return errors.New("please specify a directory with student profiles with --logs")
+	}
+
+	content.check()
+	initConcepts()
+	students, err := content.readAnswerLogs(*logs)
+	if err != nil {
+		return err
+	}
+	if len(students) == 0 {
+		return errors.New(fmt.Sprintf("no student data found in %s", *logs))
+	}
+	fitted := fitParams(students)
+
+	pf := &paramsFile{Concepts: make(map[string]*fittedParams, len(fitted))}
+	concepts := make([]*Concept, 0, len(fitted))
+	for c, p := range fitted {
+		pf.Concepts[c.shortName] = p
+		concepts = append(concepts, c)
+	}
+	sort.Slice(concepts, func(i, j int) bool {
+		return concepts[i].shortName < concepts[j].shortName
+	})
+	fmt.Printf("Fitted on %d students:\n", len(students))
+	fmt.Printf("%-20s %8s %8s %8s %8s %8s %8s\n", "concept", "pInit", "pLearn", "pSlip", "pGuess", "students", "obs")
+	for _, c := range concepts {
+		p := fitted[c]
+		fmt.Printf("%-20s %8.3f %8.3f %8.3f %8.3f %8d %8d\n", c.shortName, p.PInit, p.PLearn, p.PSlip, p.PGuess, p.Students, p.Observations)
+	}
+
+	data, err := json.MarshalIndent(pf, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*out, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Parameters written to %s\n", *out)
+	return nil
+}
+
+// loadParams loads a parameter file and sets the BKT parameters of the
+// concepts in it. Concepts that are not known are reported and skipped;
+// parameters that are not valid probabilities are an error.
+func loadParams(fname string) error {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	var pf paramsFile
+	if err := json.Unmarshal(data, &pf); err != nil {
+		return err
+	}
+	for name, p := range pf.Concepts {
+		c := findConcept(allConcepts, name)
+		if c == nil {
+			fmt.Fprintf(os.Stderr, "%s: unknown concept %s\n", fname, name)
+			continue
+		}
+		bkt := &conceptBKT{pInit: prob(p.PInit), pLearn: prob(p.PLearn), pSlip: prob(p.PSlip), pGuess: prob(p.PGuess)}
+		if err := bkt.check(); err != nil {
+			return errors.New(fmt.Sprintf("%s: concept %s: %v", fname, name, err))
+		}
+		c.bkt = bkt
+	}
+	return nil
+}
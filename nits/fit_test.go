@@ -0,0 +1,93 @@
+package nits
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestFitParams(t *testing.T) {
+	c := &Concept{name: "fit test", shortName: "fittest"}
+	allConcepts = append(allConcepts, c)
+	defer func() { allConcepts = allConcepts[:len(allConcepts)-1] }()
+	content := &Content{Questions: []Question{
+		&MultipleChoiceQuestion{
+			ShortName: "mc_fit",
+			Concepts:  []*Concept{c},
+			Answers:   []*Answer{{Text: "Yes", Correct: true}, {Text: "No"}},
+		},
+	}}
+
+	home, err := ioutil.TempDir("", "nits_fit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	// The student data is read from the profiles. The backups and a
+	// profile without data are left out.
+	students := map[string]string{
+		"alice": `[{"shortName":"mc_fit","correct":false,"subQuestion":""},{"shortName":"mc_fit","correct":true,"subQuestion":""}]`,
+		"bob":   `[{"shortName":"mc_fit","correct":true,"subQuestion":""},{"shortName":"mc_unknown","correct":true,"subQuestion":""}]`,
+		"carol": `this is not student data`,
+		"dave":  "",
+	}
+	for name, s := range students {
+		p, err := newProfile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s == "" {
+			continue
+		}
+		for _, fname := range []string{p.dataPath(), p.backupPath(1), p.backupPath(2)} {
+			if err := ioutil.WriteFile(fname, []byte(s), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := ioutil.WriteFile(path.Join(profilesDir(), "stray"), []byte(students["alice"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := content.readAnswerLogs(profilesDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("readAnswerLogs(); got:%d students, want:2", len(logs))
+	}
+	fitted := fitParams(logs)
+	p := fitted[c]
+	if p == nil {
+		t.Fatal("fitParams(); no parameters for the concept")
+	}
+	if p.Students != 2 || p.Observations != 3 {
+		t.Errorf("fitParams(); got:%d students, %d observations, want:2 students, 3 observations", p.Students, p.Observations)
+	}
+
+	// Loading checks that the parameters are probabilities.
+	fname := path.Join(home, "params.json")
+	for _, tc := range []struct {
+		params string
+		ok     bool
+	}{
+		{`{"concepts": {"fittest": {"pInit": 0.4, "pLearn": 0, "pSlip": 0.1, "pGuess": 0.2}}}`, true},
+		{`{"concepts": {"fittest": {"pInit": 1, "pLearn": 0.1, "pSlip": 0.1, "pGuess": 0.2}}}`, false},
+		{`{"concepts": {"fittest": {"pInit": 0.4, "pLearn": 1.5, "pSlip": 0.1, "pGuess": 0.2}}}`, false},
+	} {
+		if err := ioutil.WriteFile(fname, []byte(tc.params), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c.bkt = nil
+		if err := loadParams(fname); (err == nil) != tc.ok {
+			t.Errorf("loadParams(%s); got: %v", tc.params, err)
+		}
+		if tc.ok && c.getBKTParams().pInit != 0.4 {
+			t.Errorf("loadParams(%s); got: %v", tc.params, c.getBKTParams())
+		}
+	}
+}
@@ -2,14 +2,29 @@ package nits
 
 // This file contains some generic routines to walk to graph of a case.
 
+import (
+	"sort"
+	"strings"
+)
+
 // IsParentOf tests if a particular event is an indirect cause for another
 // event.
 func isParentOf(suspectedParent, child Event) bool {
+	return isParentOfSeen(suspectedParent, child, make(map[Event]interface{}))
+}
+
+// isParentOfSeen implements isParentOf. The seen set prevents it from
+// recursing infinitely on cyclic content.
+func isParentOfSeen(suspectedParent, child Event, seen map[Event]interface{}) bool {
 	if suspectedParent == child {
 		return true
 	}
+	if _, ok := seen[child]; ok {
+		return false
+	}
+	seen[child] = nil
 	for _, cause := range child.getDirectCauses() {
-		if isParentOf(suspectedParent, cause) {
+		if isParentOfSeen(suspectedParent, cause, seen) {
 			return true
 		}
 	}
@@ -27,6 +42,71 @@ func isCauseInFact(dam InjuryOrDamage, event Event) bool {
 	return false
 }
 
+// isProximateCause checks if an event is a proximate cause of some
+// damage: the damage must be in the consequences of the event through a
+// chain of events that are all foreseeable consequences of the events
+// before them.
+func isProximateCause(dam InjuryOrDamage, event Event) bool {
+	seen := make(map[Event]interface{})
+	events := []Event{event}
+	for len(events) > 0 {
+		next := make([]Event, 0)
+		for _, e := range events {
+			if _, ok := seen[e]; ok {
+				continue
+			}
+			seen[e] = nil
+			for _, d := range e.getInjuriesOrDamages() {
+				if d == dam {
+					return true
+				}
+			}
+			for _, c := range e.getConsequences() {
+				if !c.getForeseeability().breaksChain() {
+					next = append(next, c)
+				}
+			}
+		}
+		events = next
+	}
+	return false
+}
+
+// chainBreakers returns the events between an event and some damage that
+// break the chain of proximate cause.
+func chainBreakers(dam InjuryOrDamage, event Event) []Event {
+	seen := make(map[Event]interface{})
+	candidates := make([]Event, 0)
+	for _, e := range dam.getDirectCauses() {
+		if _, ok := seen[e]; !ok {
+			seen[e] = nil
+			candidates = append(candidates, e)
+		}
+		candidates = append(candidates, ancestors(e, seen)...)
+	}
+	result := make([]Event, 0)
+	for _, c := range candidates {
+		if c != event && c.getForeseeability().breaksChain() && isParentOf(event, c) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// ancestors returns all the direct and indirect causes of an event.
+func ancestors(e Event, seen map[Event]interface{}) []Event {
+	result := make([]Event, 0)
+	for _, c := range e.getDirectCauses() {
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = nil
+		result = append(result, c)
+		result = append(result, ancestors(c, seen)...)
+	}
+	return result
+}
+
 // intersectPersons returns the slice of all persons that
 // are both in a and b.
 func intersectPersons(a, b []*Person) []*Person {
@@ -63,6 +143,11 @@ func findDuties(dam InjuryOrDamage) []*Duty {
 		// next will contain the direct causes of the events we are looking at now.
 		next := make([]Event, 0)
 		for _, e := range causes {
+			// An event can be the cause of several events on the level below,
+			// so it can be in causes more than once.
+			if _, ok := seen[e]; ok {
+				continue
+			}
 			// Registers this event as seen.
 			seen[e] = nil
 			// Goes through the direct causes of this event and maybe adds them
@@ -92,10 +177,11 @@ func findDuties(dam InjuryOrDamage) []*Duty {
 
 // findDamages collects all damages that are in the direct and indirect
 // consequences of a set of events.
-// Note: This method does not have protection against infinite recursion.
 func findDamages(events []Event) []InjuryOrDamage {
 	// Collect them in a set, to prevent duplicates.
 	result := make(map[InjuryOrDamage]interface{}, 0)
+	// seen is a set that will prevent us from looping infinitely.
+	seen := make(map[Event]interface{})
 	for len(events) > 0 {
 		// Keep track of the next level down to process next.
 		next := make([]Event, 0)
@@ -103,6 +189,10 @@ func findDamages(events []Event) []InjuryOrDamage {
 		// Collects all the damages directly linked to the events
 		// at this level in the tree into the result set.
 		for _, e := range events {
+			if _, ok := seen[e]; ok {
+				continue
+			}
+			seen[e] = nil
 			if dams := e.getInjuriesOrDamages(); dams != nil {
 				for _, d := range dams {
 					result[d] = nil
@@ -138,3 +228,80 @@ func collectPersonsFromDuties(duties []*Duty) map[*Person]interface{} {
 	}
 	return m
 }
+
+// vicariouslyLiable finds the persons that are vicariously liable for the
+// acts of a set of persons, through (chains of) relationships, and that
+// are not in the set themselves.
+func (p *preprocessedCase) vicariouslyLiable(persons map[*Person]interface{}) map[*Person]interface{} {
+	result := make(map[*Person]interface{})
+	todo := make([]*Person, 0, len(persons))
+	for person := range persons {
+		todo = append(todo, person)
+	}
+	for len(todo) > 0 {
+		person := todo[0]
+		todo = todo[1:]
+		for _, r := range p.relationships {
+			if r.Subordinate != person {
+				continue
+			}
+			if _, ok := persons[r.Superior]; ok {
+				continue
+			}
+			if _, ok := result[r.Superior]; ok {
+				continue
+			}
+			result[r.Superior] = nil
+			todo = append(todo, r.Superior)
+		}
+	}
+	return result
+}
+
+// union returns the union of two sets of persons.
+func union(a, b map[*Person]interface{}) map[*Person]interface{} {
+	m := make(map[*Person]interface{}, len(a)+len(b))
+	for p := range a {
+		m[p] = nil
+	}
+	for p := range b {
+		m[p] = nil
+	}
+	return m
+}
+
+// personSet turns a slice of persons into a set.
+func personSet(persons []*Person) map[*Person]interface{} {
+	m := make(map[*Person]interface{}, len(persons))
+	for _, p := range persons {
+		m[p] = nil
+	}
+	return m
+}
+
+// namesMatch checks if a list of names entered by the student are exactly
+// the names of a set of persons (ignoring case and order).
+func namesMatch(names []string, persons map[*Person]interface{}) bool {
+	// names is the list entered by the student. Sort this list by name.
+	entered := append(make([]string, 0, len(names)), names...)
+	sort.Strings(entered)
+
+	// Collect the names of the persons (lower case) in a slice, and sort
+	// that slice by name.
+	expected := make([]string, 0, len(persons))
+	for p := range persons {
+		expected = append(expected, strings.ToLower(p.Name))
+	}
+	sort.Strings(expected)
+
+	// Now compare the two slices.
+	if len(entered) != len(expected) {
+		return false
+	}
+	for i := range entered {
+		if entered[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
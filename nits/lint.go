@@ -0,0 +1,250 @@
+package nits
+
+// This file implements the content linter. Where Content.check panics on
+// the first problem, the linter reports all the problems it can find, so
+// that authors can fix their content before publishing it.
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+)
+
+// lintIssue is a problem with the content.
+type lintIssue struct {
+	location string
+	message  string
+}
+
+// linter collects the issues in a piece of content.
+type linter struct {
+	content *Content
+	issues  []*lintIssue
+}
+
+func (l *linter) report(location, s string, args ...interface{}) {
+	l.issues = append(l.issues, &lintIssue{location: location, message: fmt.Sprintf(s, args...)})
+}
+
+// questionLocation returns the location of a question for an issue.
+func (l *linter) questionLocation(q Question) string {
+	source := "built-in"
+	if f, ok := l.content.sources[q.getShortName()]; ok {
+		source = f
+	}
+	return fmt.Sprintf("%s: question %s", source, q.getShortName())
+}
+
+// conceptLocation returns the location of a concept for an issue.
+func conceptLocation(c *Concept) string {
+	source := "built-in"
+	if f, ok := conceptSources[c]; ok {
+		source = f
+	}
+	return fmt.Sprintf("%s: concept %s", source, c.shortName)
+}
+
+// Lint implements the lint command. It checks the content in the
+// directory given as argument or with --content, or else the built-in
+// content, and prints all problems. Content files are loaded leniently, so
+// that a problem in one of them does not hide the others.
+func Lint(builtin func() *Content, args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		return errors.New("usage: nits lint [content directory]")
+	}
+	dir := *contentFlag
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	content := builtin()
+	var loadIssues []*lintIssue
+	if dir != "" {
+		var err error
+		if content, loadIssues, err = loadContent(dir, true); err != nil {
+			return err
+		}
+	}
+
+	issues := lint(content, loadIssues)
+	for _, issue := range issues {
+		fmt.Printf("%s: %s\n", issue.location, issue.message)
+	}
+	if len(issues) > 0 {
+		return errors.New(fmt.Sprintf("%d problems found", len(issues)))
+	}
+	fmt.Println("No problems found.")
+	return nil
+}
+
+// lint checks the content and all concepts and returns the issues,
+// together with the issues found while loading the content, sorted by
+// location.
+func lint(content *Content, loadIssues []*lintIssue) []*lintIssue {
+	l := &linter{content: content, issues: append([]*lintIssue(nil), loadIssues...)}
+	known := make(map[*Concept]bool, len(allConcepts))
+	for _, c := range allConcepts {
+		known[c] = true
+	}
+	used := make(map[*Concept]bool)
+
+	// checkConcepts checks the concepts that a question refers to and marks
+	// them as used.
+	checkConcepts := func(q Question, what string, concepts []*Concept) {
+		for _, c := range concepts {
+			if c == nil {
+				l.report(l.questionLocation(q), "%s refers to a nil concept", what)
+				continue
+			}
+			if !known[c] {
+				l.report(l.questionLocation(q), "%s refers to concept %s which is not in the list of all concepts", what, c.shortName)
+			}
+			used[c] = true
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, q := range content.Questions {
+		loc := l.questionLocation(q)
+		if q.getShortName() == "" {
+			l.report(loc, "question without a short name")
+		} else if names[q.getShortName()] {
+			l.report(loc, "duplicate question short name")
+		}
+		names[q.getShortName()] = true
+
+		switch q := q.(type) {
+		case *MultipleChoiceQuestion:
+			l.lintMultipleChoice(q)
+			checkConcepts(q, "question", q.Concepts)
+			for i, a := range q.Answers {
+				checkConcepts(q, fmt.Sprintf("answer %d", i+1), a.Concepts)
+			}
+		case *PropsQuestion:
+			if len(q.Propositions) < 2 {
+				l.report(loc, "less than two propositions")
+			}
+			n := 0
+			for i, p := range q.Propositions {
+				checkConcepts(q, fmt.Sprintf("proposition %d", i+1), p.Concepts)
+				n += len(p.Concepts)
+			}
+			if n == 0 {
+				l.report(loc, "no concepts")
+			}
+		case *Case:
+			l.lintCase(q)
+			checkConcepts(q, "case", q.getConcepts())
+		}
+	}
+
+	conceptNames := make(map[string]bool)
+	for _, c := range allConcepts {
+		if conceptNames[c.shortName] {
+			l.report(conceptLocation(c), "duplicate concept short name")
+		}
+		conceptNames[c.shortName] = true
+		if !used[c] {
+			l.report(conceptLocation(c), "not used by any question")
+		}
+		if c.explanation == nil || len(c.explanation.Text) == 0 {
+			l.report(conceptLocation(c), "no explanation")
+		}
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].location != l.issues[j].location {
+			return l.issues[i].location < l.issues[j].location
+		}
+		return l.issues[i].message < l.issues[j].message
+	})
+	return l.issues
+}
+
+// lintMultipleChoice does the checks of MultipleChoiceQuestion.check.
+func (l *linter) lintMultipleChoice(q *MultipleChoiceQuestion) {
+	loc := l.questionLocation(q)
+	if len(q.Answers) < 2 {
+		l.report(loc, "less than two answers")
+	}
+	n, concepts := 0, len(q.Concepts)
+	for _, a := range q.Answers {
+		if a.Correct {
+			n++
+		}
+		concepts += len(a.Concepts)
+	}
+	if n == 0 {
+		l.report(loc, "no correct answer")
+	}
+	if concepts == 0 {
+		l.report(loc, "no concepts")
+	}
+}
+
+// lintCase checks the graph of a case.
+func (l *linter) lintCase(c *Case) {
+	loc := l.questionLocation(c)
+	if len(c.RootEvents) == 0 {
+		l.report(loc, "no root events")
+		return
+	}
+	pp := c.preprocess()
+	for _, err := range pp.errors {
+		l.report(loc, "%s", err.describe())
+	}
+	acts := 0
+	for e := range pp.events {
+		if _, ok := e.(*Act); ok {
+			acts++
+		}
+	}
+	if acts == 0 {
+		l.report(loc, "no acts")
+	}
+	if len(pp.injuriesOrDamages) == 0 {
+		l.report(loc, "no injuries or damages")
+	}
+	if bars, known := pp.contributoryBars(); known && !bars {
+		for d := range pp.defences {
+			if d.Kind == ContributoryNegligenceDefence {
+				l.report(loc, "contributory negligence %q is not a defence under %s", d.Description, pp.jurisdiction.Rule)
+			}
+		}
+	}
+	for d := range pp.duties {
+		for _, p := range d.OwedTo {
+			if len(p.damages) == 0 {
+				l.report(loc, "duty %q is owed to %s, who is never harmed", d.Description, p.Name)
+			}
+		}
+	}
+	for b := range pp.brokenLegalRequirement {
+		if len(b.ProtectedPersons) == 0 {
+			l.report(loc, "statute %q protects nobody in the case", b.Description)
+		}
+		if len(b.HarmTypes) == 0 {
+			l.report(loc, "statute %q prevents no type of harm", b.Description)
+		}
+	}
+	if acts == 0 || len(pp.injuriesOrDamages) == 0 {
+		return
+	}
+	for _, tag := range c.SubQuestions {
+		if sq, ok := sqMap[tag]; !ok {
+			l.report(loc, "unknown sub question %s", tag)
+		} else if !sq.applies(pp) {
+			l.report(loc, "sub question %s can never be asked", tag)
+		}
+	}
+	for _, tag := range c.ExcludedSubQuestions {
+		if _, ok := sqMap[tag]; !ok {
+			l.report(loc, "unknown sub question %s", tag)
+		}
+	}
+	if len(c.subQuestions()) == 0 {
+		l.report(loc, "no sub question can be asked")
+	}
+}
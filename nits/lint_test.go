@@ -0,0 +1,102 @@
+package nits
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	loop1 := &PassiveEvent{ID: "loop1", Description: "First"}
+	loop2 := &PassiveEvent{ID: "loop2", Description: "Second", Consequences: []Event{loop1}}
+	loop1.Consequences = []Event{loop2}
+	content := &Content{Questions: []Question{
+		&MultipleChoiceQuestion{
+			ShortName: "mc_lint",
+			Concepts:  []*Concept{&Concept{shortName: "stray"}},
+			Answers:   []*Answer{{Text: "Yes"}},
+		},
+		&MultipleChoiceQuestion{ShortName: "mc_lint"},
+		&Case{ShortName: "case_loop", RootEvents: []Event{loop1}},
+		&Case{ShortName: "case_nothing", RootEvents: []Event{&PassiveEvent{Description: "Nothing happens"}}},
+		&Case{
+			ShortName: "case_comparative",
+			RootEvents: []Event{&PassiveEvent{
+				Description: "Something happens",
+				Defences:    []*Defence{{Kind: ContributoryNegligenceDefence, Description: "Not careful"}},
+			}},
+			Jurisdiction: &Jurisdiction{Name: "California", Rule: PureComparative},
+		},
+		&Case{ShortName: "case_statute", RootEvents: []Event{&PassiveEvent{
+			Description: "Someone speeds",
+			NegPerSe:    &BrokenLegalRequirement{Description: "Speeding"},
+		}}},
+	}}
+
+	got := make([]string, 0)
+	for _, issue := range lint(content, nil) {
+		got = append(got, issue.location+": "+issue.message)
+	}
+	all := strings.Join(got, "\n")
+	for _, want := range []string{
+		"question mc_lint: duplicate question short name",
+		"question mc_lint: less than two answers",
+		"question mc_lint: no correct answer",
+		"question mc_lint: question refers to concept stray which is not in the list of all concepts",
+		"question case_loop: cycle in event consequences: loop1 -> loop2 -> loop1",
+		"question case_nothing: no acts",
+		"question case_nothing: no injuries or damages",
+		"question case_statute: statute \"Speeding\" protects nobody in the case",
+		"question case_statute: statute \"Speeding\" prevents no type of harm",
+		"question case_comparative: contributory negligence \"Not careful\" is not a defence under pure comparative negligence",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("lint(); missing issue %q in:\n%s", want, all)
+		}
+	}
+}
+
+func TestLintLenientLoad(t *testing.T) {
+	n := len(allConcepts)
+	defer func() { allConcepts = allConcepts[:n] }()
+	dir, err := ioutil.TempDir("", "nits_lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A case with an unknown duty and a cycle, and a file that does not
+	// parse.
+	bad := strings.Replace(testContent, "duty: drive_carefully", "duty: drive_slowly", 1)
+	bad = strings.Replace(bad, "injuriesOrDamages: [bobs_leg]}", "injuriesOrDamages: [bobs_leg], consequences: [drives]}", 1)
+	for fname, data := range map[string]string{"test.yaml": bad, "broken.yaml": "questions: [this is not"} {
+		if err := ioutil.WriteFile(path.Join(dir, fname), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, issues, err := loadContent(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(content.Questions) != 2 {
+		t.Errorf("loadContent(lenient); got: %d questions, want: 2", len(content.Questions))
+	}
+
+	got := make([]string, 0)
+	for _, issue := range lint(content, issues) {
+		got = append(got, issue.location+": "+issue.message)
+	}
+	all := strings.Join(got, "\n")
+	for _, want := range []string{
+		path.Join(dir, "broken.yaml") + ": ",
+		path.Join(dir, "test.yaml") + ": question case_file: event drives: unknown duty drive_slowly",
+		path.Join(dir, "test.yaml") + ": question case_file: cycle in event consequences",
+		path.Join(dir, "test.yaml") + ": concept filetest1: no explanation",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("lint() after a lenient load; missing issue %q in:\n%s", want, all)
+		}
+	}
+}
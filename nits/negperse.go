@@ -1,10 +1,12 @@
 package nits
 
-// This file implements the Negligence Per Se sub question.
+// This file implements the Negligence Per Se sub question. It takes a
+// broken legal requirement, a damage and a plaintiff, and asks the student
+// if each of the elements of negligence per se is satisfied.
 
 import (
 	"math/rand"
-	"strings"
+	"sort"
 )
 
 type negligencePerSeSubQuestion struct{}
@@ -17,91 +19,134 @@ func (n *negligencePerSeSubQuestion) getConcepts() []*Concept {
 	return []*Concept{NegligencePerSe1}
 }
 
+func (n *negligencePerSeSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.negPerSeCandidates()) > 0
+}
+
 var _ = addSubQuestion(&negligencePerSeSubQuestion{})
 
-// ask asks the negligence per se sub question.
-func (n *negligencePerSeSubQuestion) ask(c *Case, ui *userInterface, state *studentState) bool {
-	// First we collect all the broken legal requirements into a slice and
-	// shuffle it.
-  pp := c.preprocess()
-	blrs := make([]*BrokenLegalRequirement, 0, len(pp.brokenLegalRequirement))
-	for b, _ := range pp.brokenLegalRequirement {
-		blrs = append(blrs, b)
+// negPerSeCandidate is a broken legal requirement together with a damage
+// and a plaintiff who could call in negligence per se for it.
+type negPerSeCandidate struct {
+	blr       *BrokenLegalRequirement
+	dam       InjuryOrDamage
+	plaintiff *Person
+}
+
+// negPerSeCandidates finds all combinations of broken legal requirements,
+// damages and plaintiffs in a case. Persons who broke the legal
+// requirement themselves are not plaintiffs for it.
+func (p *preprocessedCase) negPerSeCandidates() []*negPerSeCandidate {
+	result := make([]*negPerSeCandidate, 0)
+	for b := range p.brokenLegalRequirement {
+		violators := personSet(b.Persons)
+		for dam := range p.injuriesOrDamages {
+			for _, person := range dam.GetPersons() {
+				if _, ok := violators[person]; !ok {
+					result = append(result, &negPerSeCandidate{blr: b, dam: dam, plaintiff: person})
+				}
+			}
+		}
 	}
-	rand.Shuffle(len(blrs), func(i, j int) {
-		blrs[i], blrs[j] = blrs[j], blrs[i]
+	sort.Slice(result, func(i, j int) bool {
+		return p.key(result[i].blr, result[i].dam, result[i].plaintiff) < p.key(result[j].blr, result[j].dam, result[j].plaintiff)
 	})
-	// Then we find a damage that is in the direct and indirect consequences
-	// of a broken legal requirement.
-	var dams []InjuryOrDamage
-	var blr *BrokenLegalRequirement
-	for _, b := range blrs {
-		dams = findDamages(b.Consequences)
-		if len(dams) > 0 {
-			blr = b
-			break
-		}
+	return result
+}
+
+// elements returns the elements of negligence per se for a candidate: if
+// the violation caused the damage, if the plaintiff is in the protected
+// class and if the damage is the type of harm the statute is meant to
+// prevent.
+func (c *negPerSeCandidate) elements() (causation, protectedClass, harmType bool) {
+	for _, dam := range findDamages(c.blr.Consequences) {
+		causation = causation || dam == c.dam
 	}
-	// At this point blr contains the broken legal requirement and dams the
-	// damages that are in the downward chain.
-	if len(dams) == 0 || blr == nil {
-		return false
+	_, protectedClass = personSet(c.blr.ProtectedPersons)[c.plaintiff]
+	for _, h := range c.blr.HarmTypes {
+		harmType = harmType || h == c.dam.getHarmType()
 	}
-	// Shuffle the damages.
-	rand.Shuffle(len(dams), func(i, j int) {
-		dams[i], dams[j] = dams[j], dams[i]
-	})
-	// This is the damage we are going to ask about.
-	dam := dams[0]
-	// Finds the breached duties that are causes of these damages.
-	// This will find defendants.
-	duties := findDuties(dam)
-	if len(duties) == 0 {
-		return false
+	return
+}
+
+// newInstance picks a random broken legal requirement, damage and
+// plaintiff.
+func (n *negligencePerSeSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	candidates := pp.negPerSeCandidates()
+	if len(candidates) == 0 {
+		return nil
 	}
-	// Finds the persons who owed these duties.
-	persons := make([]*Person, 0)
-	for p := range collectPersonsFromDuties(duties) {
-		persons = append(persons, p)
+	cand := candidates[r.Intn(len(candidates))]
+	return pp.newInstance(n, cand.blr, cand.dam, cand.plaintiff)
+}
+
+// ask asks the negligence per se sub question.
+func (n *negligencePerSeSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	blr, ok1 := pp.ref(inst, 0).(*BrokenLegalRequirement)
+	dam, ok2 := pp.ref(inst, 1).(InjuryOrDamage)
+	plaintiff, ok3 := pp.ref(inst, 2).(*Person)
+	if !ok1 || !ok2 || !ok3 {
+		return badInstance(ui, inst)
 	}
-	rand.Shuffle(len(persons), func(i, j int) {
-		persons[i], persons[j] = persons[j], persons[i]
-	})
-	// Now find a person who has committed an act that has this damage
-	// in the indirect consequences.
-	var defendant *Person
-	outer:
-	for _, p := range persons {
-		for event, _ := range pp.events {
-			if act , ok := event.(*Act); ok && act.Person == p {
-				for _, d := range findDamages(act.Consequences) {
-					if d == dam {
-						defendant = p
-						break outer
-					}
-				}
+	cand := &negPerSeCandidate{blr: blr, dam: dam, plaintiff: plaintiff}
+	causation, protectedClass, harmType := cand.elements()
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("In this case there is the following violation of a statute or regulation:")
+		ui.println(cand.blr.Description)
+		ui.println("%s wants to call in negligence per se for this damage:", cand.plaintiff.Name)
+		ui.println(cand.dam.GetDescription())
+		ui.println("Let's check the elements one by one.")
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	// The question counts as correct if all elements were answered right
+	// the first time.
+	correct := true
+	responses := make([]string, 0)
+	element := func(question string, rightAnswer bool) bool {
+		for first := true; ; first = false {
+			answer, ret := ui.yesNo(question)
+			if ret {
+				return ret
+			}
+			responses = append(responses, yesNoText(answer))
+			if answer == rightAnswer {
+				ui.println("Correct :-)")
+				return false
+			}
+			if first {
+				correct = false
 			}
+			ui.println("Please try again :-(")
 		}
 	}
-	if defendant == nil {
-		return false
+
+	if element("Did the violation cause the damage", causation) {
+		return true
 	}
-	attempts := 0
-	for {
-		ui.newline()
-		ui.println("Looking at the following damage:")
-		ui.println(dam.GetDescription())
-		ui.println("Which legal principle can defendant %s call in?", defendant.Name)
-		words, ret := ui.getInput()
-		if ret {
-			return ret
-		}
-		if strings.Join(words, " ") == "negligence per se" {
-			ui.println("Correct!")
-			state.registerAnswer(c, n, attempts == 0)
-			return false
-		}
-		ui.println("Incorrect :-(")
-		attempts++
+	if element("Is "+cand.plaintiff.Name+" in the class of people the statute protects", protectedClass) {
+		return true
+	}
+	if element("Is "+cand.dam.getHarmType().String()+" the type of harm the statute is meant to prevent", harmType) {
+		return true
+	}
+
+	if cand.blr.ProtectedClass != "" {
+		ui.println("The statute protects %s.", cand.blr.ProtectedClass)
+	}
+	if causation && protectedClass && harmType {
+		ui.println("All elements are satisfied: %s can call in negligence per se.", cand.plaintiff.Name)
+	} else {
+		ui.println("Not all elements are satisfied: %s can not call in negligence per se.", cand.plaintiff.Name)
 	}
+	state.registerOutcome(c, n, responses, correct)
+	return false
 }
@@ -0,0 +1,30 @@
+package nits
+
+import "testing"
+
+func TestNegPerSeElements(t *testing.T) {
+	type elements struct{ causation, protectedClass, harmType bool }
+	want := map[string]elements{
+		"Rooke suffers serious injuries because of being thrown from the car": {true, true, true},
+		"The engine of Ashton's car is ruined because it ran without oil":     {false, true, true},
+	}
+	found := 0
+	for _, cand := range DefaultCase().preprocess().negPerSeCandidates() {
+		if cand.blr.Persons[0].Name != "Bruce" {
+			continue
+		}
+		w, ok := want[cand.dam.GetDescription()]
+		if !ok {
+			continue
+		}
+		found++
+		var got elements
+		got.causation, got.protectedClass, got.harmType = cand.elements()
+		if got != w {
+			t.Errorf("elements(%q); got: %+v, want: %+v", cand.dam.GetDescription(), got, w)
+		}
+	}
+	if found != len(want) {
+		t.Errorf("negPerSeCandidates(); got: %d candidates, want: %d", found, len(want))
+	}
+}
@@ -0,0 +1,83 @@
+package nits
+
+// This file contains a student model based on Performance Factors
+// Analysis (Pavlik, Cen & Koedinger, 2009). The probability that a student
+// masters a concept is a logistic function of the number of successes and
+// failures the student had with that concept.
+
+import "encoding/json"
+
+const (
+	pfaBeta  = -2.0 // Easiness of a concept.
+	pfaGamma = 0.6  // Weight of a prior success.
+	pfaRho   = -0.2 // Weight of a prior failure.
+)
+
+// pfaModel is the Performance Factors Analysis student model.
+type pfaModel struct {
+	successes map[*Concept]int
+	failures  map[*Concept]int
+}
+
+func (m *pfaModel) getName() string {
+	return "pfa"
+}
+
+func (m *pfaModel) reset() {
+	m.successes = make(map[*Concept]int)
+	m.failures = make(map[*Concept]int)
+}
+
+// update counts the answer as a success or failure for all of its
+// concepts.
+func (m *pfaModel) update(a *answer) {
+	if a.question == nil {
+		return
+	}
+	for _, c := range a.question.getTrainingConcepts(a.subQuestion) {
+		if a.correct {
+			m.successes[c]++
+		} else {
+			m.failures[c]++
+		}
+	}
+}
+
+// predict computes the mastery of every concept that has been seen.
+func (m *pfaModel) predict() (map[*Concept]float64, error) {
+	scores := make(map[*Concept]float64)
+	for c, n := range m.successes {
+		scores[c] = sigmoid(pfaBeta + pfaGamma*float64(n) + pfaRho*float64(m.failures[c]))
+	}
+	for c, n := range m.failures {
+		if _, ok := scores[c]; !ok {
+			scores[c] = sigmoid(pfaBeta + pfaRho*float64(n))
+		}
+	}
+	return scores, nil
+}
+
+// pfaState is the JSON representation of the PFA model.
+type pfaState struct {
+	Model     string         `json:"model"`
+	Successes map[string]int `json:"successes"`
+	Failures  map[string]int `json:"failures"`
+}
+
+func (m *pfaModel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&pfaState{
+		Model:     m.getName(),
+		Successes: countsByName(m.successes),
+		Failures:  countsByName(m.failures),
+	})
+}
+
+func (m *pfaModel) UnmarshalJSON(b []byte) error {
+	var v pfaState
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	m.successes = countsByConcept(v.Successes)
+	m.failures = countsByConcept(v.Failures)
+	return nil
+}
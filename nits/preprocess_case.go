@@ -2,7 +2,13 @@ package nits
 
 // This file contains the preprocessing code for a case.
 
-import "math/rand"
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
 
 // preprocessedCase is a struct that contains sets for all the individual
 // objects of a given type in a case.
@@ -12,8 +18,14 @@ type preprocessedCase struct {
 	persons                map[*Person]interface{}
 	duties                 map[*Duty]interface{}
 	claims                 map[*Claim]interface{}
+	defences               map[*Defence]interface{}
 	injuriesOrDamages      map[InjuryOrDamage]interface{}
 	brokenLegalRequirement map[*BrokenLegalRequirement]interface{}
+	relationships          []*Relationship
+	jurisdiction           *Jurisdiction
+	errors                 []*caseError            // Structural problems in the case.
+	objects                map[string]identifiable // All objects by id.
+	ids                    map[identifiable]string // The ids of all objects, authored or derived.
 }
 
 func (p *preprocessedCase) ppClaims(event Event, claims []*Claim) {
@@ -23,6 +35,13 @@ func (p *preprocessedCase) ppClaims(event Event, claims []*Claim) {
 	}
 }
 
+func (p *preprocessedCase) ppDefences(event Event, defences []*Defence) {
+	for _, defence := range defences {
+		defence.event = event
+		p.defences[defence] = nil
+	}
+}
+
 func (p *preprocessedCase) ppPersons(persons []*Person) {
 	for _, person := range persons {
 		p.persons[person] = nil
@@ -36,6 +55,9 @@ func (p *preprocessedCase) ppInjuriesOrDamages(event Event, injuriesOrDamages []
 		}
 		p.injuriesOrDamages[dam] = nil
 		p.ppPersons(dam.GetPersons())
+		for _, f := range dam.getFault() {
+			p.ppPersons([]*Person{f.Person})
+		}
 		dam.addCause(event)
 
 		for _, person := range dam.GetPersons() {
@@ -62,11 +84,13 @@ func (p *preprocessedCase) ppDuty(event Event, duty *Duty) {
 }
 
 func (p *preprocessedCase) ppEvent(parent Event, e Event) {
+	// An event can be a consequence of several events, so every parent is
+	// recorded as a cause even when the event has been seen before.
+	e.addCause(parent)
 	if _, ok := p.events[e]; ok {
 		return
 	}
 
-	e.addCause(parent)
 	p.events[e] = nil
 	p.ppEvents(e, e.getConsequences())
 
@@ -78,6 +102,14 @@ func (p *preprocessedCase) ppEvent(parent Event, e Event) {
 		p.ppClaims(e, e.getClaims())
 	}
 
+	if e.getDefences() != nil {
+		p.ppDefences(e, e.getDefences())
+	}
+
+	if r := e.getResIpsa(); r != nil && r.ExclusiveControl != nil {
+		p.ppPersons([]*Person{r.ExclusiveControl})
+	}
+
 	if e.getNegPerSe() != nil {
 		p.ppBrokenLegalRequirement(e, e.getNegPerSe())
 	}
@@ -105,42 +137,324 @@ func (c *Case) preprocess() *preprocessedCase {
 		persons:                make(map[*Person]interface{}),
 		duties:                 make(map[*Duty]interface{}),
 		claims:                 make(map[*Claim]interface{}),
+		defences:               make(map[*Defence]interface{}),
 		injuriesOrDamages:      make(map[InjuryOrDamage]interface{}),
 		brokenLegalRequirement: make(map[*BrokenLegalRequirement]interface{}),
 	}
 	p.ppEvents(nil, c.RootEvents)
+	p.jurisdiction = c.Jurisdiction
+	for _, r := range c.Relationships {
+		p.ppPersons([]*Person{r.Superior, r.Subordinate})
+		p.relationships = append(p.relationships, r)
+	}
+	p.errors = c.validate(p)
+	p.ppIDs(c)
 	c.preproc = p
 	return p
 }
 
-// findEvent finds an event by short name. Used for unit testing.
-func (p *preprocessedCase) findEvent(shortName string) Event {
+// findEvent finds an event by id. Used for unit testing.
+func (p *preprocessedCase) findEvent(id string) Event {
 	for event := range p.events {
-		if event.getShortName() == shortName {
+		if p.id(event) == id {
 			return event
 		}
 	}
 	return nil
 }
 
+// ppIDs checks the ids of the objects in a case and derives ids for the
+// objects that have none. Ids are derived the way the case exporter does
+// it, from the names and descriptions of the objects, so they only change
+// when those change. The derived ids are kept in the preprocessed case;
+// the objects themselves keep the ids they were authored with.
+func (p *preprocessedCase) ppIDs(c *Case) {
+	// The first pass finds the ids that were authored, so that the ids
+	// that are derived in the second pass do not clash with them.
+	first := newCaseExporter()
+	first.export(c)
+	owners := make(map[string]interface{})
+	duplicates := make([]string, 0)
+	for obj := range first.ids {
+		id := obj.(identifiable).getID()
+		if id == "" {
+			continue
+		}
+		if _, ok := owners[id]; ok {
+			duplicates = append(duplicates, id)
+		}
+		owners[id] = obj
+	}
+	sort.Strings(duplicates)
+	for i, id := range duplicates {
+		if i == 0 || duplicates[i-1] != id {
+			p.errors = append(p.errors, &caseError{caseName: c.ShortName, kind: duplicateIDError, id: id})
+		}
+	}
+
+	x := newCaseExporter()
+	for id := range owners {
+		x.used[id] = true
+	}
+	x.export(c)
+	p.objects = make(map[string]identifiable, len(x.ids))
+	p.ids = make(map[identifiable]string, len(x.ids))
+	for obj, id := range x.ids {
+		p.objects[id] = obj.(identifiable)
+		p.ids[obj.(identifiable)] = id
+	}
+}
+
+// id returns the id of an object in a case: the id it was authored with,
+// or else the id that was derived for it.
+func (p *preprocessedCase) id(obj identifiable) string {
+	return p.ids[obj]
+}
+
+// key returns the ids of some objects in a case as a single string, for
+// sorting.
+func (p *preprocessedCase) key(objs ...identifiable) string {
+	ids := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		ids = append(ids, p.id(obj))
+	}
+	return strings.Join(ids, " ")
+}
+
 // randomAct finds a random act.
-func (p *preprocessedCase) randomAct() *Act {
+func (p *preprocessedCase) randomAct(r *rand.Rand) *Act {
 	acts := make([]*Act, 0, len(p.events))
 	for event := range p.events {
 		if act, ok := event.(*Act); ok {
 			acts = append(acts, act)
 		}
 	}
+	sort.Slice(acts, func(i, j int) bool {
+		return p.key(acts[i]) < p.key(acts[j])
+	})
 
-	return acts[rand.Int()%len(acts)]
+	return acts[r.Int()%len(acts)]
 }
 
 // randomInjuryOrDamage finds a random injury or damage. Surprising, I know.
-func (p *preprocessedCase) randomInjuryOrDamage() InjuryOrDamage {
+func (p *preprocessedCase) randomInjuryOrDamage(r *rand.Rand) InjuryOrDamage {
 	dams := make([]InjuryOrDamage, 0, len(p.injuriesOrDamages))
 	for dam := range p.injuriesOrDamages {
 		dams = append(dams, dam)
 	}
+	p.sortDamages(dams)
+
+	return dams[r.Int()%len(dams)]
+}
+
+// sortDamages sorts damages by id. Random picks are made from sorted
+// slices, so that they do not depend on the order of map iteration.
+func (p *preprocessedCase) sortDamages(dams []InjuryOrDamage) {
+	sort.Slice(dams, func(i, j int) bool {
+		return p.key(dams[i]) < p.key(dams[j])
+	})
+}
+
+// --------------------------------------------------------------------
+
+// caseErrorKind is the kind of a structural problem in a case.
+type caseErrorKind int
+
+const (
+	cycleError           caseErrorKind = iota // Events that are their own (indirect) consequence.
+	selfConsequenceError                      // An event that is its own direct consequence.
+	negPerSeOnlyError                         // An event that can only be reached through a broken legal requirement.
+	dutyOnlyPersonError                       // A person that only appears in duties.
+	faultShareError                           // Fault shares of a damage that do not add up to 100%.
+	duplicateIDError                          // An id that is used by more than one object.
+)
+
+// caseError is a structural problem in the graph of a case. Problems like
+// these would make the graph walking code loop, or make sub questions ask
+// about things that are not in the case.
+type caseError struct {
+	caseName string
+	kind     caseErrorKind
+	events   []Event // The events involved, for cycles in order.
+	person   *Person
+	damage   InjuryOrDamage
+	total    int    // The sum of the fault shares of the damage.
+	id       string // The duplicate id.
+}
+
+// eventName returns a name for an event in an error message.
+func eventName(e Event) string {
+	if e.getID() != "" {
+		return e.getID()
+	}
+	return fmt.Sprintf("%q", e.getDescription())
+}
 
-	return dams[rand.Int()%len(dams)]
+// describe describes the problem, without the name of the case.
+func (e *caseError) describe() string {
+	switch e.kind {
+	case cycleError:
+		names := make([]string, 0, len(e.events))
+		for _, event := range e.events {
+			names = append(names, eventName(event))
+		}
+		return "cycle in event consequences: " + strings.Join(names, " -> ")
+	case selfConsequenceError:
+		return fmt.Sprintf("event %s is a consequence of itself", eventName(e.events[0]))
+	case negPerSeOnlyError:
+		return fmt.Sprintf("event %s can only be reached through a broken legal requirement", eventName(e.events[0]))
+	case dutyOnlyPersonError:
+		return fmt.Sprintf("person %s only appears in duties", e.person.Name)
+	case faultShareError:
+		return fmt.Sprintf("fault shares of %q add up to %d%%, not 100%%", e.damage.GetDescription(), e.total)
+	case duplicateIDError:
+		return fmt.Sprintf("id %s is used by more than one object", e.id)
+	}
+	return "unknown problem"
+}
+
+func (e *caseError) Error() string {
+	return fmt.Sprintf("case %s: %s", e.caseName, e.describe())
+}
+
+// validate checks the structure of a preprocessed case.
+func (c *Case) validate(p *preprocessedCase) []*caseError {
+	result := make([]*caseError, 0)
+	add := func(kind caseErrorKind, events []Event, person *Person) {
+		result = append(result, &caseError{caseName: c.ShortName, kind: kind, events: events, person: person})
+	}
+
+	// Looks for cycles with a depth first search. The consequences of a
+	// broken legal requirement are not consequences of the event it belongs
+	// to (that event is often one of them), so they are searched as extra
+	// roots.
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[Event]int)
+	path := make([]Event, 0)
+	roots := append(make([]Event, 0), c.RootEvents...)
+	var visit func(e Event)
+	visit = func(e Event) {
+		switch state[e] {
+		case done:
+			return
+		case visiting:
+			if path[len(path)-1] == e {
+				add(selfConsequenceError, []Event{e}, nil)
+				return
+			}
+			i := len(path) - 1
+			for path[i] != e {
+				i--
+			}
+			add(cycleError, append(append(make([]Event, 0), path[i:]...), e), nil)
+			return
+		}
+		state[e] = visiting
+		path = append(path, e)
+		for _, next := range e.getConsequences() {
+			visit(next)
+		}
+		if b := e.getNegPerSe(); b != nil {
+			roots = append(roots, b.Consequences...)
+		}
+		path = path[:len(path)-1]
+		state[e] = done
+	}
+	for i := 0; i < len(roots); i++ {
+		visit(roots[i])
+	}
+
+	// Finds the events that can not be reached from the root events
+	// through consequences alone.
+	reachable := make(map[Event]interface{})
+	events := c.RootEvents
+	for len(events) > 0 {
+		next := make([]Event, 0)
+		for _, e := range events {
+			if _, ok := reachable[e]; ok {
+				continue
+			}
+			reachable[e] = nil
+			next = append(next, e.getConsequences()...)
+		}
+		events = next
+	}
+	for e := range p.events {
+		if _, ok := reachable[e]; !ok {
+			add(negPerSeOnlyError, []Event{e}, nil)
+		}
+	}
+
+	// Finds the persons that appear in duties but not in any event, damage,
+	// claim, relationship or broken legal requirement.
+	involved := make(map[*Person]interface{})
+	for e := range p.events {
+		if act, ok := e.(*Act); ok && act.Person != nil {
+			involved[act.Person] = nil
+		}
+		if r := e.getResIpsa(); r != nil && r.ExclusiveControl != nil {
+			involved[r.ExclusiveControl] = nil
+		}
+	}
+	for dam := range p.injuriesOrDamages {
+		for _, person := range dam.GetPersons() {
+			involved[person] = nil
+		}
+		for _, f := range dam.getFault() {
+			involved[f.Person] = nil
+		}
+	}
+	for claim := range p.claims {
+		involved[claim.Person] = nil
+	}
+	for _, r := range p.relationships {
+		involved[r.Superior] = nil
+		involved[r.Subordinate] = nil
+	}
+	for b := range p.brokenLegalRequirement {
+		for _, person := range b.Persons {
+			involved[person] = nil
+		}
+	}
+	for person := range p.persons {
+		if _, ok := involved[person]; !ok {
+			add(dutyOnlyPersonError, nil, person)
+		}
+	}
+
+	// Checks that the fault for a damage is divided completely.
+	for dam := range p.injuriesOrDamages {
+		if len(dam.getFault()) == 0 {
+			continue
+		}
+		total := 0
+		for _, f := range dam.getFault() {
+			total += f.Percentage
+		}
+		if total != 100 {
+			result = append(result, &caseError{caseName: c.ShortName, kind: faultShareError, damage: dam, total: total})
+		}
+	}
+
+	// Map iteration order is random; this keeps the errors stable.
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].describe() < result[j].describe()
+	})
+	return result
+}
+
+// err returns all structural problems in the case as a single error, or
+// nil if there are none.
+func (p *preprocessedCase) err() error {
+	if len(p.errors) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(p.errors))
+	for _, e := range p.errors {
+		messages = append(messages, e.Error())
+	}
+	return errors.New(strings.Join(messages, "\n"))
 }
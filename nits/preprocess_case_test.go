@@ -0,0 +1,114 @@
+package nits
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	alice := &Person{Name: "Alice"}
+	bob := &Person{Name: "Bob"}
+	carol := &Person{Name: "Carol"}
+	injury := &BodilyInjury{Description: "Bob is hurt", Persons: []*Person{bob}}
+	hidden := &PassiveEvent{ID: "hidden", Description: "Only through the statute"}
+	loop1 := &PassiveEvent{ID: "loop1", InjuriesOrDamages: []InjuryOrDamage{injury}}
+	loop2 := &PassiveEvent{ID: "loop2", Consequences: []Event{loop1}}
+	loop1.Consequences = []Event{loop2}
+	self := &PassiveEvent{ID: "self"}
+	self.Consequences = []Event{self}
+	act := &Act{
+		ID:           "act",
+		Person:       alice,
+		Consequences: []Event{loop1, self},
+		Duty:         &Duty{Description: "Be careful", OwedFrom: []*Person{alice}, OwedTo: []*Person{bob, carol}},
+		NegPerSe:     &BrokenLegalRequirement{Persons: []*Person{alice}, Consequences: []Event{hidden}},
+	}
+	c := &Case{ShortName: "case_bad", RootEvents: []Event{act}}
+
+	got := make(map[string]bool)
+	for _, err := range c.preprocess().errors {
+		got[err.Error()] = true
+	}
+	for _, want := range []string{
+		"case case_bad: cycle in event consequences: loop1 -> loop2 -> loop1",
+		"case case_bad: event self is a consequence of itself",
+		"case case_bad: event hidden can only be reached through a broken legal requirement",
+		"case case_bad: person Carol only appears in duties",
+	} {
+		if !got[want] {
+			t.Errorf("preprocess(); missing error %q, got: %v", want, got)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("preprocess(); got: %d errors, want: 4", len(got))
+	}
+
+	// The graph walking functions must terminate on cycles.
+	if isParentOf(self, act) {
+		t.Errorf("isParentOf(self, act); got: true, want: false")
+	}
+	if dams := findDamages([]Event{loop2}); len(dams) != 1 {
+		t.Errorf("findDamages(loop2); got: %d damages, want: 1", len(dams))
+	}
+
+	if err := DefaultCase().preprocess().err(); err != nil {
+		t.Errorf("DefaultCase().preprocess(); got: %v, want: no errors", err)
+	}
+}
+
+func TestIDs(t *testing.T) {
+	c := DefaultCase()
+	pp := c.preprocess()
+	if pp.findEvent("plows") == nil {
+		t.Errorf("findEvent(plows); got: nil, want: the authored id to be kept")
+	}
+	n := len(pp.events) + len(pp.duties) + len(pp.injuriesOrDamages) + len(pp.claims) + len(pp.defences) + len(pp.brokenLegalRequirement)
+	if len(pp.objects) < n {
+		t.Errorf("preprocess(); got: %d objects with an id, want: at least %d", len(pp.objects), n)
+	}
+	for id, obj := range pp.objects {
+		if pp.id(obj) != id {
+			t.Errorf("preprocess(); got: object with id %s under %s", pp.id(obj), id)
+		}
+	}
+	// Derived ids are not written into the objects, and they are made from
+	// the descriptions.
+	for dam := range pp.injuriesOrDamages {
+		if dam.getID() != "" {
+			t.Errorf("preprocess(); got: id %s in a damage without an authored id", dam.getID())
+		}
+		if dam.GetDescription() == "Rooke suffers serious injuries because of being thrown from the car" && pp.id(dam) != "rooke_suffers_serious_injuries" {
+			t.Errorf("preprocess(); got: id %s for Rooke's injuries, want: rooke_suffers_serious_injuries", pp.id(dam))
+		}
+	}
+
+	// Derived ids do not change between runs.
+	again := DefaultCase().preprocess()
+	for id, obj := range pp.objects {
+		if other, ok := again.objects[id]; !ok || other.(hasLabel).getLabel() != obj.(hasLabel).getLabel() {
+			t.Errorf("preprocess() twice; object %s differs", id)
+		}
+	}
+
+	// Derived ids do not clash with authored ones.
+	first := &PassiveEvent{Description: "First"}
+	second := &PassiveEvent{ID: "first", Description: "Second"}
+	first.Consequences = []Event{second}
+	c = &Case{ShortName: "case_ids", RootEvents: []Event{first}}
+	pp = c.preprocess()
+	if err := pp.err(); err != nil || pp.id(first) == pp.id(second) {
+		t.Errorf("preprocess(); got: ids %s and %s (%v), want: different ids", pp.id(first), pp.id(second), err)
+	}
+
+	// Adding an object does not change the ids of the others.
+	root := &PassiveEvent{Description: "A new first event", Consequences: []Event{first}}
+	c = &Case{ShortName: "case_ids", RootEvents: []Event{root}}
+	if got := c.preprocess().id(first); got != pp.id(first) {
+		t.Errorf("preprocess() with an extra event; got: id %s, want: %s", got, pp.id(first))
+	}
+
+	// Authored ids must be unique.
+	third := &PassiveEvent{ID: "first", Description: "Third"}
+	second.Consequences = []Event{third}
+	c = &Case{ShortName: "case_dup", RootEvents: []Event{&PassiveEvent{ID: "root", Consequences: []Event{second}}}}
+	if err := c.preprocess().err(); err == nil || err.Error() != "case case_dup: id first is used by more than one object" {
+		t.Errorf("preprocess() with a duplicate id; got: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package nits
+
+// This file implements the prima facie sub question. It walks the student
+// through the four elements of a prima facie case for a plaintiff and a
+// damage: the damage itself, the duty owed to the plaintiff, the breach of
+// that duty and causation. Every element is a step of its own, so that the
+// answers train the concepts of the elements separately.
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// The steps of the prima facie walkthrough.
+var (
+	primaFacieDamage    = addStep(&step{parent: "primaFacie", tag: "primaFacie.damage", concepts: []*Concept{InjuryOrDamage0}})
+	primaFacieDuty      = addStep(&step{parent: "primaFacie", tag: "primaFacie.duty", concepts: []*Concept{Duty1}})
+	primaFacieBreach    = addStep(&step{parent: "primaFacie", tag: "primaFacie.breach", concepts: []*Concept{Breach1}})
+	primaFacieCausation = addStep(&step{parent: "primaFacie", tag: "primaFacie.causation", concepts: []*Concept{CauseInFact1}})
+)
+
+// maxDamageChoices is the maximum number of choices in the damage step.
+const maxDamageChoices = 4
+
+type primaFacieSubQuestion struct{}
+
+func (pf *primaFacieSubQuestion) getTag() string {
+	return "primaFacie"
+}
+
+func (pf *primaFacieSubQuestion) getConcepts() []*Concept {
+	return []*Concept{primaFacie2}
+}
+
+func (pf *primaFacieSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.plaintiffs()) > 0
+}
+
+var _ = addSubQuestion(&primaFacieSubQuestion{})
+
+// plaintiffs finds the persons that suffered an injury or damage, sorted by
+// id.
+func (p *preprocessedCase) plaintiffs() []*Person {
+	persons := make([]*Person, 0, len(p.persons))
+	for person := range p.persons {
+		if len(person.damages) > 0 {
+			persons = append(persons, person)
+		}
+	}
+	sort.Slice(persons, func(i, j int) bool {
+		return p.key(persons[i]) < p.key(persons[j])
+	})
+	return persons
+}
+
+// damageChoices returns the choices for the damage step: the damage and a
+// few things that are not damages of the plaintiff.
+func (p *preprocessedCase) damageChoices(plaintiff *Person, dam InjuryOrDamage, r *rand.Rand) []*Answer {
+	others := make([]string, 0)
+	for d := range p.injuriesOrDamages {
+		if _, ok := plaintiff.damages[d]; !ok {
+			others = append(others, d.GetDescription())
+		}
+	}
+	for e := range p.events {
+		if len(e.getInjuriesOrDamages()) == 0 {
+			others = append(others, e.getDescription())
+		}
+	}
+	sort.Strings(others)
+	r.Shuffle(len(others), func(i, j int) {
+		others[i], others[j] = others[j], others[i]
+	})
+	if len(others) > maxDamageChoices-1 {
+		others = others[:maxDamageChoices-1]
+	}
+	answers := []*Answer{{Text: dam.GetDescription(), Correct: true}}
+	for _, o := range others {
+		answers = append(answers, &Answer{Text: o})
+	}
+	r.Shuffle(len(answers), func(i, j int) {
+		answers[i], answers[j] = answers[j], answers[i]
+	})
+	return answers
+}
+
+// dutiesOwedTo returns the duties from a list that are owed to a person.
+func dutiesOwedTo(duties []*Duty, person *Person) []*Duty {
+	result := make([]*Duty, 0)
+	for _, d := range duties {
+		for _, p := range d.OwedTo {
+			if p == person {
+				result = append(result, d)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// personNames returns the sorted names of a set of persons.
+func personNames(persons map[*Person]interface{}) []string {
+	names := make([]string, 0, len(persons))
+	for p := range persons {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newInstance picks a random plaintiff, one of their damages and, if there
+// is one, a duty owed to them. The duty does not have to be one that led to
+// the damage; then there is no causation.
+func (pf *primaFacieSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	plaintiffs := pp.plaintiffs()
+	if len(plaintiffs) == 0 {
+		return nil
+	}
+	plaintiff := plaintiffs[r.Intn(len(plaintiffs))]
+	dams := make([]InjuryOrDamage, 0, len(plaintiff.damages))
+	for d := range plaintiff.damages {
+		dams = append(dams, d)
+	}
+	pp.sortDamages(dams)
+	dam := dams[r.Intn(len(dams))]
+	duties := dutiesOwedTo(pp.sortedDuties(), plaintiff)
+	if len(duties) == 0 {
+		return pp.newInstance(pf, plaintiff, dam)
+	}
+	return pp.newInstance(pf, plaintiff, dam, duties[r.Intn(len(duties))])
+}
+
+// ask asks the prima facie sub question.
+func (pf *primaFacieSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	plaintiff, ok1 := pp.ref(inst, 0).(*Person)
+	dam, ok2 := pp.ref(inst, 1).(InjuryOrDamage)
+	if !ok1 || !ok2 {
+		return badInstance(ui, inst)
+	}
+	duties := dutiesOwedTo(pp.sortedDuties(), plaintiff)
+	duty, ok := pp.ref(inst, 2).(*Duty)
+	if len(duties) > 0 && (!ok || len(dutiesOwedTo([]*Duty{duty}, plaintiff)) == 0) {
+		return badInstance(ui, inst)
+	}
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("Let's see if %s has a prima facie case. We will go through the four elements one by one.", plaintiff.Name)
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	// The walkthrough counts as correct if all steps were answered right
+	// the first time.
+	correct := true
+	responses := make([]string, 0)
+	answerStep := func(sq subQuestion, r []string) {
+		state.registerAnswer(c, sq, r)
+		correct = correct && len(r) == 1
+		responses = append(responses, r...)
+	}
+
+	// Step 1: the damage.
+	ui.println("1) Which of the following is an injury or damage that %s suffered?", plaintiff.Name)
+	choices := pp.damageChoices(plaintiff, dam, state.rng)
+	ui.newline()
+	ui.printAnswers(choices)
+	ui.newline()
+	r, ret := askChoice(ui, choices)
+	if ret {
+		return ret
+	}
+	answerStep(primaFacieDamage, r)
+
+	// Step 2: the duty.
+	owedFrom := collectPersonsFromDuties(duties)
+	r = make([]string, 0)
+	for {
+		ui.newline()
+		ui.println("2) Who owed %s a duty of care in this case? (Enter just a . if nobody did.)", plaintiff.Name)
+		names, ret := ui.getNames()
+		if ret {
+			return ret
+		}
+		r = append(r, strings.Join(names, ", "))
+		if namesMatch(names, owedFrom) {
+			ui.println("Correct!")
+			break
+		}
+		ui.println("Incorrect :-(")
+	}
+	answerStep(primaFacieDuty, r)
+	if len(duties) == 0 {
+		ui.println("Without a duty there is no prima facie case for this damage.")
+		state.registerOutcome(c, pf, responses, correct)
+		return false
+	}
+
+	// Step 3: the breach.
+	ui.newline()
+	ui.println("3) Which of these events breached the duty: %s?", duty.Description)
+	choices = pp.breachChoices(duty, state.rng)
+	ui.newline()
+	ui.printAnswers(choices)
+	ui.newline()
+	r, ret = askChoice(ui, choices)
+	if ret {
+		return ret
+	}
+	answerStep(primaFacieBreach, r)
+
+	// Step 4: causation.
+	rightAnswer := isCauseInFact(dam, duty.event)
+	r = make([]string, 0)
+	for {
+		ui.newline()
+		ui.println("4) Is this breach a cause-in-fact of the damage?")
+		answer, ret := ui.yesNo("Your answer")
+		if ret {
+			return ret
+		}
+		r = append(r, yesNoText(answer))
+		if answer == rightAnswer {
+			ui.println("Correct :-)")
+			break
+		}
+		ui.println("Please try again :-(")
+	}
+	answerStep(primaFacieCausation, r)
+
+	if rightAnswer {
+		ui.println("All four elements are there: %s has a prima facie case against %s.", plaintiff.Name, strings.Join(personNames(personSet(duty.OwedFrom)), ", "))
+	} else {
+		ui.println("Without causation there is no prima facie case for this damage.")
+	}
+	state.registerOutcome(c, pf, responses, correct)
+	return false
+}
@@ -0,0 +1,255 @@
+package nits
+
+// This file implements student profiles. Every student has a named
+// profile with its own answer history and readline history, so that
+// students can share a machine.
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var profileFlag = flag.String("profile", "", "Name of the student profile to use")
+
+// validProfileName is what a profile name looks like.
+var validProfileName = regexp.MustCompile("^[a-z0-9_-]+$")
+
+// profile is a named student profile.
+type profile struct {
+	name string
+}
+
+// profilesDir returns the directory that contains all profiles.
+func profilesDir() string {
+	return path.Join(mustUserHomeDir(), ".nits", "profiles")
+}
+
+func (p *profile) dir() string {
+	return path.Join(profilesDir(), p.name)
+}
+
+// dataPath returns the location of the student data of this profile.
+func (p *profile) dataPath() string {
+	return path.Join(p.dir(), "data")
+}
+
+// backupPath returns the location of the nth backup of the student data
+// of this profile. Backup 1 is the most recent one.
+func (p *profile) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", p.dataPath(), n)
+}
+
+// historyPath returns the location of the readline history of this
+// profile.
+func (p *profile) historyPath() string {
+	return path.Join(p.dir(), "readline")
+}
+
+// listProfiles returns the names of all profiles, sorted.
+func listProfiles() ([]string, error) {
+	files, err := ioutil.ReadDir(profilesDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, fi := range files {
+		if fi.IsDir() {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// findProfile finds an existing profile by name.
+func findProfile(name string) (*profile, error) {
+	if !validProfileName.MatchString(name) {
+		return nil, errors.New(fmt.Sprintf("no profile named %s", name))
+	}
+	p := &profile{name: name}
+	fi, err := os.Stat(p.dir())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("no profile named %s", name))
+	}
+	if !fi.IsDir() {
+		return nil, errors.New(fmt.Sprintf("%s is not a directory", p.dir()))
+	}
+	return p, nil
+}
+
+// newProfile creates a new profile.
+func newProfile(name string) (*profile, error) {
+	if !validProfileName.MatchString(name) {
+		return nil, errors.New("profile names can only contain a-z, 0-9, _ and -")
+	}
+	p := &profile{name: name}
+	if _, err := os.Stat(p.dir()); err == nil {
+		return nil, errors.New(fmt.Sprintf("profile %s already exists", name))
+	}
+	if err := os.MkdirAll(p.dir(), 0755); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// migrateLegacyData turns the student data from the days before profiles
+// (~/.nits_data) into a profile named "default", if there are no profiles
+// yet. The old file is left alone.
+func migrateLegacyData() error {
+	if names, err := listProfiles(); err != nil || len(names) > 0 {
+		return err
+	}
+	data, err := ioutil.ReadFile(path.Join(mustUserHomeDir(), ".nits_data"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p, err := newProfile("default")
+	if err != nil {
+		return err
+	}
+	println("Student data in ~/.nits_data copied to profile", p.name)
+	return writeFileAtomic(p.dataPath(), data, 0644)
+}
+
+// selectProfile selects the profile to start with. This is the profile
+// given on the command line, or else the student gets to pick (or create)
+// one.
+func selectProfile(ui *userInterface) *profile {
+	if err := migrateLegacyData(); err != nil {
+		ui.error("Migrating ~/.nits_data failed: %s", err)
+	}
+	if *profileFlag != "" {
+		if p, err := findProfile(*profileFlag); err == nil {
+			return p
+		}
+		p, err := newProfile(*profileFlag)
+		if err != nil {
+			panic(err)
+		}
+		ui.println("Created new profile %s.", p.name)
+		return p
+	}
+
+	names, err := listProfiles()
+	if err != nil {
+		ui.error("Cannot list profiles: %s", err)
+	}
+	if len(names) > 0 {
+		ui.println("Known profiles: %s", strings.Join(names, ", "))
+	}
+	ui.pushCommandContext(&CommandContext{
+		description: "Selecting a student profile",
+		commands: []*Command{
+			{
+				aliases: []string{"exit", "quit"},
+				help:    "Exits NITS.",
+				executor: func([]string) bool {
+					os.Exit(0)
+					return true
+				},
+			},
+		},
+	})
+	defer ui.popCommandContext()
+	ui.pushPrompt("Profile name? ")
+	defer ui.popPrompt()
+
+	for {
+		words, _ := ui.getInput()
+		if len(words) != 1 {
+			ui.error("Please enter a one-word profile name.")
+			continue
+		}
+		if p, err := findProfile(words[0]); err == nil {
+			return p
+		}
+		create, _ := ui.yesNo(fmt.Sprintf("Create new profile %s", words[0]))
+		if !create {
+			continue
+		}
+		p, err := newProfile(words[0])
+		if err != nil {
+			ui.error("%s", err)
+			continue
+		}
+		return p
+	}
+}
+
+// switchProfile saves the data of the current profile and continues with
+// another one.
+func (s *studentState) switchProfile(ui *userInterface, p *profile) {
+	if err := s.saveUserData(); err != nil {
+		ui.error("Saving failed: %s", err)
+	}
+	s.profile = p
+	s.backedUp = false
+	s.reset()
+	ui.setHistoryPath(p.historyPath())
+	warnings, err := s.loadUserData()
+	if err != nil && !os.IsNotExist(err) {
+		ui.error("User data *not* loaded: %s", err)
+	}
+	for _, w := range warnings {
+		ui.println("%s", w)
+	}
+	ui.println("Now using profile %s.", p.name)
+}
+
+// profileCommand is the UI command that manages profiles. It returns
+// true if the profile was switched, which abandons the current question.
+func profileCommand(ui *userInterface, state *studentState, words []string) bool {
+	if len(words) < 2 {
+		ui.println("Current profile: %s", state.profile.name)
+		return false
+	}
+	switch words[1] {
+	case "list":
+		names, err := listProfiles()
+		if err != nil {
+			ui.error("Cannot list profiles: %s", err)
+			return false
+		}
+		for _, name := range names {
+			marker := " "
+			if name == state.profile.name {
+				marker = "*"
+			}
+			ui.println("%s %s", marker, name)
+		}
+	case "switch", "new":
+		if len(words) != 3 {
+			ui.error("Usage: profile %s <name>", words[1])
+			return false
+		}
+		var p *profile
+		var err error
+		if words[1] == "switch" {
+			p, err = findProfile(words[2])
+		} else {
+			p, err = newProfile(words[2])
+		}
+		if err != nil {
+			ui.error("%s", err)
+			return false
+		}
+		state.switchProfile(ui, p)
+		return true
+	default:
+		ui.error("Usage: profile [list|switch <name>|new <name>]")
+	}
+	return false
+}
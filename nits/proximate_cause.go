@@ -0,0 +1,120 @@
+package nits
+
+// This file contains the implementation of the proximate cause sub
+// question. It asks if a damage was a foreseeable consequence of an act
+// that was a cause-in-fact of that damage.
+
+import (
+	"math/rand"
+	"sort"
+)
+
+type proximateCauseSubQuestion struct{}
+
+func (pc *proximateCauseSubQuestion) getTag() string {
+	return "proximateCause"
+}
+
+func (pc *proximateCauseSubQuestion) getConcepts() []*Concept {
+	return []*Concept{Foreseeability1}
+}
+
+func (pc *proximateCauseSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.causeInFactPairs()) > 0
+}
+
+var _ = addSubQuestion(&proximateCauseSubQuestion{})
+
+// causeInFactPair is an act together with a damage that it is a
+// cause-in-fact of.
+type causeInFactPair struct {
+	act *Act
+	dam InjuryOrDamage
+}
+
+// causeInFactPairs finds all the acts in a case together with the damages
+// they are a cause-in-fact of.
+func (p *preprocessedCase) causeInFactPairs() []*causeInFactPair {
+	result := make([]*causeInFactPair, 0)
+	for event := range p.events {
+		act, ok := event.(*Act)
+		if !ok {
+			continue
+		}
+		for dam := range p.injuriesOrDamages {
+			if isCauseInFact(dam, act) {
+				result = append(result, &causeInFactPair{act: act, dam: dam})
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return p.key(result[i].act, result[i].dam) < p.key(result[j].act, result[j].dam)
+	})
+	return result
+}
+
+// newInstance picks a random act and a damage it is a cause-in-fact of.
+// Only those make sense to ask about; otherwise there is no causation to
+// begin with.
+func (pc *proximateCauseSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	pairs := pp.causeInFactPairs()
+	if len(pairs) == 0 {
+		return nil
+	}
+	pair := pairs[r.Intn(len(pairs))]
+	return pp.newInstance(pc, pair.act, pair.dam)
+}
+
+// ask asks the sub question.
+func (pc *proximateCauseSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	act, ok1 := pp.ref(inst, 0).(*Act)
+	dam, ok2 := pp.ref(inst, 1).(InjuryOrDamage)
+	if !ok1 || !ok2 {
+		return badInstance(ui, inst)
+	}
+	pair := &causeInFactPair{act: act, dam: dam}
+	rightAnswer := isProximateCause(pair.dam, pair.act)
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("In this case, the act:")
+		ui.println(pair.act.Description)
+		ui.println("is a cause-in-fact of this injury or property damage:")
+		ui.println(pair.dam.GetDescription())
+		ui.println("Is it also a proximate cause, i.e. was the damage a foreseeable consequence of the act?")
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses := make([]string, 0)
+
+	for {
+		answer, ret := ui.yesNo("Your answer")
+		if ret {
+			return ret
+		}
+		responses = append(responses, yesNoText(answer))
+		if answer != rightAnswer {
+			ui.println("Please try again :-(")
+			continue
+		}
+		ui.println("Correct :-)")
+		// Tells the student what broke the chain of causation.
+		if !rightAnswer {
+			for _, e := range chainBreakers(pair.dam, pair.act) {
+				if e.getForeseeability() == Superseding {
+					ui.println("- %s: a superseding cause", e.getDescription())
+				} else {
+					ui.println("- %s: not foreseeable", e.getDescription())
+				}
+			}
+		}
+		state.registerAnswer(c, pc, responses)
+		return false
+	}
+}
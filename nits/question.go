@@ -4,7 +4,8 @@ package nits
 // multiple choice questions and proposition questions.
 
 import (
-	"math/rand"
+	"fmt"
+	"strings"
 )
 
 // --------------------------------------------------------------------
@@ -144,13 +145,34 @@ func makeAnswerMap(n int) answerMap {
 	m := make(answerMap)
 
 	for i:=1; i <= n; i++ {
-		r := string('a' + i - 1)
+		r := string(rune('a' + i - 1))
 		m[r] = []string{r}
 	}
 
 	return m
 }
 
+// askChoice lets the student choose from a list of answers until they
+// choose a correct one. It returns all the responses.
+func askChoice(ui *userInterface, answers []*Answer) ([]string, bool) {
+	responses := make([]string, 0)
+	possibleAnswers := makeAnswerMap(len(answers))
+
+	for {
+		s, ret := ui.getAnswer(possibleAnswers)
+		if ret {
+			return responses, ret
+		}
+		answer := answers[s[0]-'a']
+		responses = append(responses, answer.Text)
+		if answer.Correct {
+			ui.println("Correct :-)")
+			return responses, false
+		}
+		ui.println("Incorrect :-(")
+	}
+}
+
 // ask asks a multiple choice question.
 func (q *MultipleChoiceQuestion) ask(ui *userInterface, state *studentState) {
 	// Shuffles the answers, making sure that None Of The Above is always last.
@@ -163,7 +185,7 @@ func (q *MultipleChoiceQuestion) ask(ui *userInterface, state *studentState) {
 			answers = append(answers, a)
 		}
 	}
-	rand.Shuffle(len(answers), func(i, j int) {
+	state.rng.Shuffle(len(answers), func(i, j int) {
 		answers[i], answers[j] = answers[j], answers[i]
 	})
 	if noneOfTheAbove != nil {
@@ -184,7 +206,7 @@ func (q *MultipleChoiceQuestion) ask(ui *userInterface, state *studentState) {
 	defer ui.popPrompt()
 	defer ui.popCommandContext()
 
-	attempts := 0
+	responses := make([]string, 0)
 	possibleAnswers := makeAnswerMap(len(answers))
 
 	for {
@@ -193,13 +215,13 @@ func (q *MultipleChoiceQuestion) ask(ui *userInterface, state *studentState) {
 			return
 		}
 		answer := s[0] - 'a'
+		responses = append(responses, answers[answer].Text)
 		if answers[answer].Correct {
 			ui.println("Correct :-)")
-			state.registerAnswer(q, nil, attempts == 0)
+			state.registerAnswer(q, nil, responses)
 			return
 		}
 		ui.println("Incorrect :-(")
-		attempts++
 	}
 }
 
@@ -281,6 +303,18 @@ func romanNumeral(number int) string {
 	return roman
 }
 
+// propsAnswerText returns the text of an answer to a proposition
+// question with n propositions, e.g. "I is true, II is false". The
+// answer is a bitmap with a bit per proposition.
+func propsAnswerText(answer byte, n int) string {
+	parts := make([]string, 0, n)
+	for j := 0; j < n; j++ {
+		parts = append(parts, fmt.Sprintf("%s is %t", romanNumeral(j+1), answer%2 == 1))
+		answer >>= 1
+	}
+	return strings.Join(parts, ", ")
+}
+
 // ask asks a proposition question.
 func (q *PropsQuestion) ask(ui *userInterface, state *studentState) {
 	displayQuestion := func([]string) bool {
@@ -333,7 +367,7 @@ func (q *PropsQuestion) ask(ui *userInterface, state *studentState) {
 	defer ui.popPrompt()
 	defer ui.popCommandContext()
 
-	attempts := 0
+	responses := make([]string, 0)
 	possibleAnswers := makeAnswerMap(1 << len(q.Propositions))
 
 outer:
@@ -343,18 +377,18 @@ outer:
 			return
 		}
 		answer := s[0] - 'a'
+		responses = append(responses, propsAnswerText(answer, len(q.Propositions)))
 		// Check the bitmap implied in the answer and see if the student
 		// got each proposition right.
 		for _, prop := range q.Propositions {
 			if (answer%2 == 0 && prop.True) || (answer%2 == 1 && !prop.True) {
 				ui.println("Incorrect :-(")
-				attempts++
 				continue outer
 			}
 			answer >>= 1
 		}
 		ui.println("Correct :-)")
-		state.registerAnswer(q, nil, attempts == 0)
+		state.registerAnswer(q, nil, responses)
 		return
 	}
 }
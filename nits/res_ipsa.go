@@ -0,0 +1,133 @@
+package nits
+
+// This file implements the res ipsa loquitur sub question. It asks if a
+// plaintiff can call in res ipsa loquitur for an event that is marked with
+// the elements of the doctrine.
+
+import (
+	"math/rand"
+	"sort"
+)
+
+type resIpsaSubQuestion struct{}
+
+func (r *resIpsaSubQuestion) getTag() string {
+	return "resIpsa"
+}
+
+func (r *resIpsaSubQuestion) getConcepts() []*Concept {
+	return []*Concept{ResIpsaLoquitur1}
+}
+
+func (r *resIpsaSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.resIpsaCandidates()) > 0
+}
+
+var _ = addSubQuestion(&resIpsaSubQuestion{})
+
+// resIpsaCandidate is an event with res ipsa markers together with a
+// person that suffered a damage in its consequences.
+type resIpsaCandidate struct {
+	event     Event
+	dam       InjuryOrDamage
+	plaintiff *Person
+}
+
+// resIpsaCandidates finds all the events with res ipsa markers in a case,
+// together with the damages they led to and the persons who suffered them.
+func (p *preprocessedCase) resIpsaCandidates() []*resIpsaCandidate {
+	result := make([]*resIpsaCandidate, 0)
+	for e := range p.events {
+		if e.getResIpsa() == nil {
+			continue
+		}
+		for _, dam := range findDamages([]Event{e}) {
+			for _, person := range dam.GetPersons() {
+				result = append(result, &resIpsaCandidate{event: e, dam: dam, plaintiff: person})
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return p.key(result[i].event, result[i].dam, result[i].plaintiff) < p.key(result[j].event, result[j].dam, result[j].plaintiff)
+	})
+	return result
+}
+
+// resIpsaApplies tells if res ipsa loquitur applies: it is not known how the
+// event happened, events like it do not happen without negligence and
+// someone other than the plaintiff was in exclusive control.
+func (c *resIpsaCandidate) resIpsaApplies() bool {
+	r := c.event.getResIpsa()
+	return r.UnknownMechanism && r.OrdinarilyNegligent && r.ExclusiveControl != nil && r.ExclusiveControl != c.plaintiff
+}
+
+// newInstance picks a random event with res ipsa markers, a damage it led
+// to and a person who suffered it.
+func (r *resIpsaSubQuestion) newInstance(pp *preprocessedCase, rng *rand.Rand) *sqInstance {
+	candidates := pp.resIpsaCandidates()
+	if len(candidates) == 0 {
+		return nil
+	}
+	cand := candidates[rng.Intn(len(candidates))]
+	return pp.newInstance(r, cand.event, cand.dam, cand.plaintiff)
+}
+
+// ask asks the res ipsa loquitur sub question.
+func (r *resIpsaSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	event, ok1 := pp.ref(inst, 0).(Event)
+	dam, ok2 := pp.ref(inst, 1).(InjuryOrDamage)
+	plaintiff, ok3 := pp.ref(inst, 2).(*Person)
+	if !ok1 || !ok2 || !ok3 || event.getResIpsa() == nil {
+		return badInstance(ui, inst)
+	}
+	cand := &resIpsaCandidate{event: event, dam: dam, plaintiff: plaintiff}
+	rightAnswer := cand.resIpsaApplies()
+	markers := cand.event.getResIpsa()
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("In this case the following happened:")
+		ui.println(cand.event.getDescription())
+		ui.println("which led to:")
+		ui.println(cand.dam.GetDescription())
+		ui.println("Can %s call in res ipsa loquitur?", cand.plaintiff.Name)
+		ui.newline()
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses := make([]string, 0)
+
+	for {
+		answer, ret := ui.yesNo("Your answer")
+		if ret {
+			return ret
+		}
+		responses = append(responses, yesNoText(answer))
+		if answer == rightAnswer {
+			ui.println("Correct :-)")
+			break
+		}
+		ui.println("Please try again :-(")
+	}
+	if !markers.UnknownMechanism {
+		ui.println("- It is known how this happened, so the plaintiff can just prove negligence.")
+	}
+	if !markers.OrdinarilyNegligent {
+		ui.println("- Things like this can happen without anyone being negligent.")
+	}
+	switch markers.ExclusiveControl {
+	case nil:
+		ui.println("- Nobody was in exclusive control of what caused this.")
+	case cand.plaintiff:
+		ui.println("- %s was in control of what caused this.", cand.plaintiff.Name)
+	default:
+		ui.println("- %s was in exclusive control of what caused this.", markers.ExclusiveControl.Name)
+	}
+	state.registerAnswer(c, r, responses)
+	return false
+}
@@ -0,0 +1,32 @@
+package nits
+
+import "testing"
+
+func TestResIpsaApplies(t *testing.T) {
+	byrne := &Person{Name: "Byrne"}
+	boadle := &Person{Name: "Boadle"}
+	injury := &BodilyInjury{Description: "Byrne is hurt", Persons: []*Person{byrne}}
+	falls := &PassiveEvent{
+		Description:       "A barrel falls",
+		InjuriesOrDamages: []InjuryOrDamage{injury},
+		ResIpsa:           &ResIpsa{UnknownMechanism: true, OrdinarilyNegligent: true, ExclusiveControl: boadle},
+	}
+	c := &Case{ShortName: "case_barrel", RootEvents: []Event{falls}}
+
+	candidates := c.preprocess().resIpsaCandidates()
+	if len(candidates) != 1 {
+		t.Fatalf("resIpsaCandidates(); got: %d candidates, want: 1", len(candidates))
+	}
+	if !candidates[0].resIpsaApplies() {
+		t.Errorf("resIpsaApplies(); got: false, want: true")
+	}
+	falls.ResIpsa.ExclusiveControl = byrne
+	if candidates[0].resIpsaApplies() {
+		t.Errorf("resIpsaApplies() with the plaintiff in control; got: true, want: false")
+	}
+	falls.ResIpsa.ExclusiveControl = boadle
+	falls.ResIpsa.UnknownMechanism = false
+	if candidates[0].resIpsaApplies() {
+		t.Errorf("resIpsaApplies() with a known mechanism; got: true, want: false")
+	}
+}
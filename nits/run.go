@@ -4,21 +4,18 @@ package nits
 // This file contains the outermost function of NITS.
 
 import (
+	"flag"
 	"fmt"
-	"math/rand"
 	"os"
 	"runtime"
-	"time"
 )
 
+var seedFlag = flag.Int64("seed", 0, "Seed for the random number generator, to replay a session (default: from the clock)")
+
 // Run runs NITS on some content.
 func Run(content *Content) {
 	content.check()
 
-	// This initialization of the random generator is not cryptographically
-	// secure, but it's good enough for our purpose.
-	rand.Seed(time.Now().UnixNano())
-
 	println("NITS 1.0 -- An ITS for negligence")
 	println("            (c) Copyright 2020  Jos Visser <josvisser66@gmail.com>")
 	println()
@@ -26,11 +23,55 @@ func Run(content *Content) {
 	println()
 	println("Running on", runtime.GOOS)
 
+	if *paramsFlag != "" {
+		if err := loadParams(*paramsFlag); err != nil {
+			panic(fmt.Sprintf("Cannot load BKT parameters: %v", err))
+		}
+		println("BKT parameters loaded from", *paramsFlag)
+	}
 	initBKT()
 	initConcepts()
-	state := newStudentState(content)
-	ui := newUserInterface()
-	defer ui.rl.Close()
+	model, err := newStudentModel(*modelFlag)
+	if err != nil {
+		panic(err)
+	}
+	println("Using student model", model.getName())
+	state := newStudentState(content, model)
+	if isFlagSet("seed") {
+		state.setSeed(*seedFlag)
+	}
+	// A scripted session runs headless and must not touch the student data
+	// of the profile, unless autosaving was asked for explicitly.
+	if *scriptFlag != "" && !isFlagSet("autosave") {
+		*autosaveFlag = false
+	}
+	saveAtEnd := *scriptFlag == "" || *autosaveFlag
+	var ui *userInterface
+	if *scriptFlag != "" {
+		lines, err := readScript(*scriptFlag)
+		if err != nil {
+			panic(fmt.Sprintf("Cannot read script: %v", err))
+		}
+		ui = newScriptedUserInterface(lines, os.Stdout)
+		// At the end of the script the session ends as if the student
+		// exited NITS.
+		ui.onEndOfScript = func() {
+			ui.newline()
+			if state.profile != nil && saveAtEnd {
+				if err := state.saveUserData(); err != nil {
+					println("Saving failed:", err.Error())
+				}
+			}
+			os.Exit(0)
+		}
+	} else {
+		ui = newUserInterface()
+	}
+	defer ui.term.close()
+
+	state.profile = selectProfile(ui)
+	ui.setHistoryPath(state.profile.historyPath())
+	ui.println("Hello %s!", state.profile.name)
 
 	// Pushes the outermost command context.
 	ui.pushCommandContext(&CommandContext{
@@ -67,14 +108,26 @@ func Run(content *Content) {
 					return false
 				},
 			},
+			{
+				aliases: []string{"profile"},
+				global:  true,
+				help:    "Student profiles: profile list, profile switch <name>, profile new <name>.",
+				executor: func(words []string) bool {
+					return profileCommand(ui, state, words)
+				},
+			},
 			{
 				aliases: []string{"load"},
 				global:  true,
 				help:    "Load student data",
 				executor: func([]string) bool {
-					if err := state.loadUserData(); err != nil {
+					warnings, err := state.loadUserData()
+					if err != nil {
 						ui.error("Loading failed: %s", err)
 					}
+					for _, w := range warnings {
+						ui.println("%s", w)
+					}
 					return false
 				},
 			},
@@ -93,16 +146,35 @@ func Run(content *Content) {
 	})
 	defer ui.popCommandContext()
 
-	if err := state.loadUserData(); err != nil {
+	if warnings, err := state.loadUserData(); os.IsNotExist(err) {
+		ui.println("No user data yet for this profile.")
+	} else if err != nil {
 		ui.error("User data *not* loaded: %s", err)
 	} else {
 		ui.println("User data restored.")
+		for _, w := range warnings {
+			ui.println("%s", w)
+		}
 	}
 
 	ui.newline()
 
+	// If something goes wrong in the middle of the session we still want
+	// to keep the student's work.
+	defer func() {
+		if r := recover(); r != nil {
+			if saveAtEnd {
+				if err := state.saveUserData(); err != nil {
+					println("Saving after a crash failed:", err.Error())
+				}
+			}
+			panic(r)
+		}
+	}()
+
 	for {
 		if next := state.selectQuestion(); next != nil {
+			state.startQuestion()
 			next.ask(ui, state)
 		} else {
 			ui.println("We are out of questions!")
@@ -110,7 +182,9 @@ func Run(content *Content) {
 		}
 	}
 
-	state.saveUserData()
+	if saveAtEnd {
+		state.saveUserData()
+	}
 }
 
 // check checks the content. Mostly delegates to the check methods
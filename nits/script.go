@@ -0,0 +1,88 @@
+package nits
+
+// This file implements scripted sessions. A script is a text file with the
+// input lines of a session; lines that start with a # are comments. The
+// scripted terminal feeds these lines to the UI and writes the output, with
+// the prompts and the input lines, as a transcript of the session. This
+// makes it possible to run NITS headlessly and to test the questions
+// against golden transcripts.
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var scriptFlag = flag.String("script", "", "File with the input lines of a session to run without a terminal; it only saves the student data with --autosave")
+
+// scriptWidth is the width of the output of a scripted session.
+const scriptWidth = 80
+
+// errEndOfScript is returned by a scripted terminal when it runs out of
+// input lines.
+var errEndOfScript = errors.New("end of script")
+
+// scriptTerminal is a terminal that reads its input lines from a script
+// and writes its output to a writer.
+type scriptTerminal struct {
+	lines  []string
+	prompt string
+	out    io.Writer
+}
+
+func (t *scriptTerminal) readLine() (string, error) {
+	if len(t.lines) == 0 {
+		return "", errEndOfScript
+	}
+	line := t.lines[0]
+	t.lines = t.lines[1:]
+	fmt.Fprintf(t.out, "%s%s\n", t.prompt, line)
+	return line, nil
+}
+
+func (t *scriptTerminal) setPrompt(prompt string) {
+	t.prompt = prompt
+}
+
+func (t *scriptTerminal) setHistoryPath(string) {}
+
+func (t *scriptTerminal) print(s string) {
+	io.WriteString(t.out, s)
+}
+
+func (t *scriptTerminal) width() int {
+	return scriptWidth
+}
+
+func (t *scriptTerminal) close() error {
+	return nil
+}
+
+// newScriptedUserInterface creates a UI driver object that takes its input
+// from a list of lines and writes its output to a writer.
+func newScriptedUserInterface(lines []string, out io.Writer) *userInterface {
+	return newUserInterfaceOn(&scriptTerminal{lines: lines, out: out})
+}
+
+// readScript reads the input lines from a script file, leaving out the
+// comments.
+func readScript(fname string) ([]string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,163 @@
+package nits
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path"
+	"sort"
+	"testing"
+)
+
+var updateFlag = flag.Bool("update", false, "Write the transcripts of the scripted tests to testdata")
+
+// barrelCase returns a small case in which res ipsa loquitur applies.
+func barrelCase() *Case {
+	byrne := &Person{Name: "Byrne"}
+	boadle := &Person{Name: "Boadle"}
+	injury := &BodilyInjury{Description: "Byrne is hurt", Persons: []*Person{byrne}}
+	falls := &PassiveEvent{
+		Description:       "A barrel of flour falls from the window of Boadle's warehouse",
+		InjuriesOrDamages: []InjuryOrDamage{injury},
+		ResIpsa:           &ResIpsa{UnknownMechanism: true, OrdinarilyNegligent: true, ExclusiveControl: boadle},
+	}
+	return &Case{
+		Text:       []string{"Byrne walks past Boadle's warehouse when a barrel of flour falls on him."},
+		ShortName:  "case_barrel",
+		RootEvents: []Event{falls},
+	}
+}
+
+// runScript asks a question with the input from testdata/<name>.script and
+// compares the output with testdata/<name>.transcript. With -update the
+// transcript is written instead.
+func runScript(t *testing.T, name string, q Question, ask func(*userInterface, *studentState)) {
+	lines, err := readScript(path.Join("testdata", name+".script"))
+	if err != nil {
+		t.Fatalf("readScript(%s); got: %v", name, err)
+	}
+	useBackend(t, &nativeBKT{})
+	model, _ := newStudentModel("bkt")
+	state := newStudentState(&Content{Questions: []Question{q}}, model)
+	state.setSeed(1)
+	var out bytes.Buffer
+	term := &scriptTerminal{lines: lines, out: &out}
+	ui := newUserInterfaceOn(term)
+	ui.onEndOfScript = func() {
+		t.Errorf("%s: the script ended before the question was answered", name)
+	}
+
+	state.startQuestion()
+	ask(ui, state)
+	if len(term.lines) > 0 {
+		t.Errorf("%s: %d lines of the script were not used", name, len(term.lines))
+	}
+	if len(state.answers) == 0 {
+		t.Errorf("%s: no answers registered", name)
+	}
+
+	fname := path.Join("testdata", name+".transcript")
+	if *updateFlag {
+		if err := ioutil.WriteFile(fname, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("%s: the transcript differs from %s; got:\n%s", name, fname, out.String())
+	}
+}
+
+// runSubQuestion asks a sub question of a case with the input from a
+// script in testdata, as runScript does.
+func runSubQuestion(t *testing.T, name string, c *Case, sq subQuestion) {
+	// The text of the case would only make the transcripts longer.
+	c.Text = nil
+	runScript(t, name, c, func(ui *userInterface, state *studentState) {
+		c.withContext(ui, state, func() {
+			if inst := sq.newInstance(c.preprocess(), state.rng); inst == nil {
+				t.Errorf("newInstance() for %s; got: nil", sq.getTag())
+			} else if c.askInstance(ui, state, inst) {
+				t.Errorf("askInstance(%s); got: true, want: false", inst)
+			}
+		})
+	})
+}
+
+func TestScriptedQuestions(t *testing.T) {
+	mc := &MultipleChoiceQuestion{
+		ShortName: "q_duty",
+		Question:  []string{"Who owes a duty of care?"},
+		Concepts:  []*Concept{Duty1},
+		Answers: []*Answer{
+			{Text: "Everybody who could foresee harm to the plaintiff", Correct: true},
+			{Text: "Only the government"},
+			{Text: "Nobody"},
+		},
+	}
+	runScript(t, "multiple_choice", mc, mc.ask)
+
+	props := &PropsQuestion{
+		ShortName: "q_props",
+		Propositions: []*Proposition{
+			{Proposition: "A duty can be owed to more than one person.", Concepts: []*Concept{Duty1}, True: true},
+			{Proposition: "A breach needs an intention to harm.", Concepts: []*Concept{Breach1}},
+		},
+	}
+	runScript(t, "props", props, props.ask)
+}
+
+func TestScriptedSubQuestions(t *testing.T) {
+	tags := make([]string, 0, len(sqMap))
+	for tag := range sqMap {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		sq := sqMap[tag]
+		c := DefaultCase()
+		if !sq.applies(c.preprocess()) {
+			c = barrelCase()
+		}
+		if !sq.applies(c.preprocess()) {
+			t.Errorf("%s does not apply to any of the test cases", tag)
+			continue
+		}
+		runSubQuestion(t, tag, c, sq)
+	}
+}
+
+func TestScriptedSupersedingCause(t *testing.T) {
+	david := &Person{Name: "David"}
+	kevin := &Person{Name: "Kevin"}
+	injury := &BodilyInjury{Description: "Kevin sustains bodily injuries", Persons: []*Person{kevin}}
+	hits := &PassiveEvent{Description: "A piece of the broken telephone pole hits Kevin", InjuriesOrDamages: []InjuryOrDamage{injury}}
+	snaps := &PassiveEvent{
+		Description:    "The badly built telephone pole snaps in two",
+		Consequences:   []Event{hits},
+		Foreseeability: Superseding,
+	}
+	swerves := &Act{
+		Description:  "David, without looking, swerves into a car that hits the telephone pole",
+		Person:       david,
+		Consequences: []Event{snaps},
+	}
+	c := &Case{ShortName: "case_pole", RootEvents: []Event{swerves}}
+	runSubQuestion(t, "proximateCause_superseding", c, sqMap["proximateCause"])
+}
+
+func TestScriptedPrimaFacieWithoutCausation(t *testing.T) {
+	ann := &Person{Name: "Ann"}
+	bob := &Person{Name: "Bob"}
+	fence := &Duty{Description: "Keep the fence around the garden in good repair", OwedFrom: []*Person{ann}, OwedTo: []*Person{bob}}
+	bite := &BodilyInjury{Description: "Bob is bitten by a stray dog", Persons: []*Person{bob}}
+	bites := &PassiveEvent{Description: "A stray dog bites Bob on the street", InjuriesOrDamages: []InjuryOrDamage{bite}}
+	neglects := &Act{Description: "Ann lets the fence fall into disrepair", Person: ann, Duty: fence}
+	c := &Case{ShortName: "case_fence", RootEvents: []Event{neglects, bites}}
+	runSubQuestion(t, "primaFacie_no_causation", c, sqMap["primaFacie"])
+}
@@ -0,0 +1,156 @@
+package nits
+
+// This file contains the abstraction for the model of the student's
+// knowledge and its Bayesian Knowledge Tracing implementation. The model
+// is selected at startup, so that different models can be compared on the
+// same content.
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+var modelFlag = flag.String("model", "bkt", "Student model to use: bkt, elo or pfa")
+
+// StudentModel is a model of the knowledge of a student. It is updated
+// with every answer the student gives and predicts the mastery of every
+// concept involved in these answers. A model can be serialized to and
+// from JSON.
+type StudentModel interface {
+	getName() string
+	reset()
+	update(a *answer)
+	predict() (map[*Concept]float64, error)
+	json.Marshaler
+	json.Unmarshaler
+}
+
+// newStudentModel creates a student model by name.
+func newStudentModel(name string) (StudentModel, error) {
+	var m StudentModel
+	switch name {
+	case "bkt":
+		m = &bktModel{backend: backend}
+	case "elo":
+		m = &eloModel{}
+	case "pfa":
+		m = &pfaModel{}
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown student model: %s", name))
+	}
+	m.reset()
+	return m, nil
+}
+
+// conceptScores is the JSON representation of a set of scores per
+// concept. It is keyed by the short name of the concept.
+type conceptScores map[string]float64
+
+// toConceptScores converts a map of scores to its JSON representation.
+func toConceptScores(m map[*Concept]float64) conceptScores {
+	result := make(conceptScores, len(m))
+	for c, v := range m {
+		result[c.shortName] = v
+	}
+	return result
+}
+
+// fromConceptScores converts the JSON representation of scores back to a
+// map. Concepts that no longer exist are dropped.
+func (cs conceptScores) fromConceptScores() map[*Concept]float64 {
+	result := make(map[*Concept]float64, len(cs))
+	for name, v := range cs {
+		if c := findConcept(allConcepts, name); c != nil {
+			result[c] = v
+		}
+	}
+	return result
+}
+
+// countsByName converts a map of counts per concept to a map keyed on
+// the short name of the concept, and back.
+func countsByName(m map[*Concept]int) map[string]int {
+	result := make(map[string]int, len(m))
+	for c, n := range m {
+		result[c.shortName] = n
+	}
+	return result
+}
+
+func countsByConcept(m map[string]int) map[*Concept]int {
+	result := make(map[*Concept]int, len(m))
+	for name, n := range m {
+		if c := findConcept(allConcepts, name); c != nil {
+			result[c] = n
+		}
+	}
+	return result
+}
+
+// --------------------------------------------------------------------
+
+// bktModel is the student model that uses Bayesian Knowledge Tracing.
+// The actual work is done by the BKT backend, which needs the whole answer
+// history. The model uses the backend that was in use when it was created.
+type bktModel struct {
+	backend bktBackend
+	answers []*answer
+	scores  map[*Concept]float64 // Scores as of the last prediction.
+}
+
+func (m *bktModel) getName() string {
+	return "bkt"
+}
+
+func (m *bktModel) reset() {
+	m.answers = make([]*answer, 0)
+	m.scores = make(map[*Concept]float64)
+}
+
+func (m *bktModel) update(a *answer) {
+	m.answers = append(m.answers, a)
+}
+
+// predict runs the BKT backend on the answers.
+func (m *bktModel) predict() (map[*Concept]float64, error) {
+	if len(m.answers) == 0 {
+		return m.scores, nil
+	}
+	if m.backend == nil {
+		return nil, errors.New("no BKT backend")
+	}
+	scores, err := m.backend.train(m.answers)
+	if err != nil {
+		return nil, err
+	}
+	m.scores = scores
+	return scores, nil
+}
+
+// MarshalJSON marshals the last predicted scores.
+func (m *bktModel) MarshalJSON() ([]byte, error) {
+	v := map[string]interface{}{
+		"model":  m.getName(),
+		"scores": toConceptScores(m.scores),
+	}
+	if m.backend != nil {
+		v["backend"] = m.backend.getName()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON restores the scores. They will be replaced as soon as the
+// model is updated with new answers.
+func (m *bktModel) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Scores conceptScores `json:"scores"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	m.reset()
+	m.scores = v.Scores.fromConceptScores()
+	return nil
+}
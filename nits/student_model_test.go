@@ -0,0 +1,78 @@
+package nits
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// useBackend makes a test use a BKT backend, and restores the backend
+// that was in use when the test is done.
+func useBackend(t *testing.T, b bktBackend) {
+	old := backend
+	backend = b
+	t.Cleanup(func() {
+		backend = old
+	})
+}
+
+func TestStudentModels(t *testing.T) {
+	useBackend(t, &nativeBKT{fit: false})
+	q := &MultipleChoiceQuestion{
+		ShortName: "mc_test",
+		Concepts:  []*Concept{Plaintiff0},
+		Answers: []*Answer{
+			{Text: "Yes", Concepts: []*Concept{Defendant0}, Correct: true},
+			{Text: "No"},
+		},
+	}
+
+	for _, name := range []string{"bkt", "elo", "pfa"} {
+		m, err := newStudentModel(name)
+		if err != nil {
+			t.Fatalf("newStudentModel(%s): %v", name, err)
+		}
+		prev := 0.0
+		var want map[*Concept]float64
+		for i := 0; i < 5; i++ {
+			m.update(&answer{questionShortName: q.ShortName, question: q, correct: true})
+			scores, err := m.predict()
+			if err != nil {
+				t.Fatalf("%s: predict: %v", name, err)
+			}
+			if scores[Plaintiff0] <= prev {
+				t.Errorf("%s: after %d correct answers; got:%f, want more than %f", name, i+1, scores[Plaintiff0], prev)
+			}
+			prev = scores[Plaintiff0]
+			want = scores
+		}
+
+		// A model that is restored from its serialized form predicts the
+		// same scores.
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", name, err)
+		}
+		m2, _ := newStudentModel(name)
+		if err := json.Unmarshal(data, m2); err != nil {
+			t.Fatalf("%s: unmarshal: %v", name, err)
+		}
+		scores, _ := m2.predict()
+		for _, c := range []*Concept{Plaintiff0, Defendant0} {
+			if scores[c] != want[c] {
+				t.Errorf("%s: restored model: %s; got:%f, want:%f", name, c.shortName, scores[c], want[c])
+			}
+		}
+	}
+
+	// A model without a backend can still be saved.
+	if _, err := json.Marshal(&bktModel{}); err != nil {
+		t.Errorf("marshal a bktModel without a backend; got: %v", err)
+	}
+	if _, err := (&bktModel{answers: []*answer{{question: q}}}).predict(); err == nil {
+		t.Error("predict() without a backend; got: nil error, want: error")
+	}
+
+	if _, err := newStudentModel("magic"); err == nil {
+		t.Error("newStudentModel(magic); got:nil error, want:error")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -43,12 +44,56 @@ type CommandContext struct {
 	commands    []*Command
 }
 
+// terminal is what the user interface reads its input from and writes its
+// output to.
+type terminal interface {
+	readLine() (string, error)
+	setPrompt(prompt string)
+	setHistoryPath(p string)
+	print(s string)
+	width() int
+	close() error
+}
+
+// readlineTerminal is the terminal of an interactive session.
+type readlineTerminal struct {
+	rl *readline.Instance
+}
+
+func (t *readlineTerminal) readLine() (string, error) {
+	return t.rl.Readline()
+}
+
+func (t *readlineTerminal) setPrompt(prompt string) {
+	t.rl.SetPrompt(prompt)
+}
+
+func (t *readlineTerminal) setHistoryPath(p string) {
+	t.rl.SetHistoryPath(p)
+}
+
+func (t *readlineTerminal) print(s string) {
+	t.rl.Terminal.Print(s)
+}
+
+func (t *readlineTerminal) width() int {
+	return t.rl.Terminal.GetConfig().FuncGetWidth()
+}
+
+func (t *readlineTerminal) close() error {
+	return t.rl.Close()
+}
+
 // userInterface is the abstract representation of the text based ui.
 type userInterface struct {
-	rl                  *readline.Instance
+	term                terminal
 	column              int
 	promptStack         []string
 	commandContextStack []*CommandContext
+	// onEndOfScript is called when a scripted session runs out of input.
+	// If it returns, the input functions return as if a command wants
+	// every context to terminate.
+	onEndOfScript func()
 }
 
 // pushCommandContext pushes a command context onto the stack.
@@ -107,13 +152,18 @@ func (ui *userInterface) maybeExecuteCommand(words []string) (bool, bool) {
 // pushPrompt pushes a prompt string onto the prompt stack.
 func (ui *userInterface) pushPrompt(t string) {
 	ui.promptStack = append(ui.promptStack, t)
-	ui.rl.SetPrompt(t)
+	ui.term.setPrompt(t)
 }
 
 // popPrompt pops a prompt string from the prompt stack.
 func (ui *userInterface) popPrompt() {
 	ui.promptStack = ui.promptStack[:len(ui.promptStack)-1]
-	ui.rl.SetPrompt(ui.promptStack[len(ui.promptStack)-1])
+	ui.term.setPrompt(ui.promptStack[len(ui.promptStack)-1])
+}
+
+// setHistoryPath changes the file in which the readline history is kept.
+func (ui *userInterface) setHistoryPath(p string) {
+	ui.term.setHistoryPath(p)
 }
 
 // mustUserHomeDir returns the location of the user's home directory
@@ -126,14 +176,10 @@ func mustUserHomeDir() string {
 	}
 }
 
-// newUserInterface creates a new UI driver object.
+// newUserInterface creates a new UI driver object for an interactive
+// session.
 func newUserInterface() *userInterface {
-	var err error
-
-	ui := &userInterface{
-		promptStack: make([]string, 0, 10),
-	}
-	ui.rl, err = readline.NewEx(&readline.Config{
+	rl, err := readline.NewEx(&readline.Config{
 		HistoryFile: path.Join(mustUserHomeDir(), ".nits_readline"),
 		// AutoComplete:    completer,
 		InterruptPrompt: "^C",
@@ -146,14 +192,23 @@ func newUserInterface() *userInterface {
 		panic(err)
 	}
 
+	log.SetOutput(rl.Stderr())
+	return newUserInterfaceOn(&readlineTerminal{rl: rl})
+}
+
+// newUserInterfaceOn creates a new UI driver object on a terminal.
+func newUserInterfaceOn(term terminal) *userInterface {
+	ui := &userInterface{
+		term:        term,
+		promptStack: make([]string, 0, 10),
+	}
 	ui.pushPrompt("Your wish is my command! ")
-	log.SetOutput(ui.rl.Stderr())
 	return ui
 }
 
 // newline generates a newline onto the output stream.
 func (ui *userInterface) newline() {
-	ui.rl.Terminal.PrintRune('\n')
+	ui.term.print("\n")
 	ui.column = 0
 }
 
@@ -176,20 +231,19 @@ func (ui *userInterface) error(s string, args ...interface{}) {
 // in the middle.
 func (ui *userInterface) print(s string, args ...interface{}) {
 	t := fmt.Sprintf(s, args...)
-	term := ui.rl.Terminal
-	width := term.GetConfig().FuncGetWidth()
+	width := ui.term.width()
 	words := strings.Split(t, " ")
 
 	for i, word := range words {
 		l := len(word)
 		if l+ui.column+1 > width {
-			term.PrintRune('\n')
+			ui.term.print("\n")
 			ui.column = 0
 		}
 		if ui.column > 0 && i > 0 {
-			term.PrintRune(' ')
+			ui.term.print(" ")
 		}
-		ui.rl.Terminal.Print(word)
+		ui.term.print(word)
 		ui.column += l + 1
 	}
 }
@@ -241,12 +295,88 @@ func (ui *userInterface) yesNo(question string) (bool, bool) {
 	return answer == "yes", ret
 }
 
+// yesNoText returns the text of a yes/no answer.
+func yesNoText(yes bool) string {
+	if yes {
+		return "yes"
+	}
+	return "no"
+}
+
+// getAmount gets an amount of dollars from the user. Dollar signs and
+// thousands separators are allowed.
+func (ui *userInterface) getAmount() (int, bool) {
+	for {
+		words, ret := ui.getInput()
+		if ret {
+			return 0, ret
+		}
+		if len(words) != 1 {
+			ui.error("Please enter a single amount.")
+			continue
+		}
+		n, err := strconv.Atoi(strings.NewReplacer("$", "", ",", "").Replace(words[0]))
+		if err != nil || n < 0 {
+			ui.error("Please enter a whole number of dollars.")
+			continue
+		}
+		return n, false
+	}
+}
+
+// getNames gets a list of one-word names from the user, one name per
+// line, until the user enters a . on a line of its own. The user gets to
+// confirm the list before it is returned.
+func (ui *userInterface) getNames() ([]string, bool) {
+	for {
+		ui.println("(Enter one name per line, finish with a . on a line of its own)")
+		names := make([]string, 0)
+
+		for {
+			words, ret := ui.getInput()
+			if ret {
+				return nil, ret
+			}
+			if len(words) == 0 {
+				continue
+			}
+			if len(words) > 1 {
+				ui.println("Please enter one word names only, finish with a . on a line of its own")
+				continue
+			}
+			if words[0] == "." {
+				break
+			}
+			names = append(names, words[0])
+		}
+
+		ui.println("You entered:")
+		for _, name := range names {
+			ui.println("- %s", name)
+		}
+
+		yes, ret := ui.yesNo("Is this correct")
+		if ret {
+			return nil, ret
+		}
+		if yes {
+			return names, false
+		}
+		ui.println("Ok, try again")
+	}
+}
+
 // getInput returns a line of input (split into lower case words on
 // spaces), while executing any commands that are valid in the context.
 func (ui *userInterface) getInput() ([]string, bool) {
 	for {
-		line, err := ui.rl.Readline()
-		if err == readline.ErrInterrupt {
+		line, err := ui.term.readLine()
+		if err == errEndOfScript {
+			if ui.onEndOfScript != nil {
+				ui.onEndOfScript()
+			}
+			return nil, true
+		} else if err == readline.ErrInterrupt {
 			continue
 		} else if err == io.EOF {
 			ui.error("Please use exit to leave NITS.")
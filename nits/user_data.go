@@ -0,0 +1,382 @@
+package nits
+
+// This file contains the code to save and load the student data. The
+// student data is a versioned JSON envelope that contains all the answers
+// the student gave, with enough detail to analyze how the student
+// answered. Version 1 of the format was a bare JSON array of answers; it
+// is still read, and upgraded when the data is saved again.
+//
+// The data is saved by writing a temporary file and renaming it over the
+// old one, so that a crash halfway through a save never leaves a damaged
+// file behind. The first save of a session rotates the previous data into
+// a number of backups, which are used if the data can not be read.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// userDataVersion is the current version of the student data format.
+const userDataVersion = 2
+
+var (
+	autosaveFlag = flag.Bool("autosave", true, "Save the student data after every answer")
+	backupsFlag  = flag.Int("backups", 3, "Number of backups of the student data to keep")
+)
+
+// userData is the envelope in which the student data is saved.
+type userData struct {
+	Version int             `json:"version"`
+	Content string          `json:"content"` // Fingerprint of the content the answers refer to.
+	Saved   time.Time       `json:"saved"`
+	Model   json.RawMessage `json:"model,omitempty"` // State of the student model, for analysis.
+	Answers []*answer       `json:"answers"`
+}
+
+// MarshalJson marshals an answer object to a JSON object.
+func (a *answer) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{})
+	m["shortName"] = a.questionShortName
+	m["correct"] = a.correct
+	if a.subQuestion == nil {
+		// Keeps the tag of sub questions that could not be found.
+		m["subQuestion"] = a.subQuestionTag
+	} else {
+		m["subQuestion"] = a.subQuestion.getTag()
+	}
+	if !a.time.IsZero() {
+		m["time"] = a.time.Format(time.RFC3339)
+	}
+	// Answers from version 1 of the format have no responses.
+	if len(a.responses) > 0 {
+		m["attempts"] = len(a.responses)
+		m["responses"] = a.responses
+		m["durationMs"] = a.duration.Milliseconds()
+	}
+	if len(a.instance) > 0 {
+		m["instance"] = a.instance
+	}
+	// The seed of the session makes it possible to replay it.
+	if a.seed != nil {
+		m["seed"] = *a.seed
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON unmarshals a JSON object back to an answer. The fields
+// that were added in version 2 of the format are optional.
+func (a *answer) UnmarshalJSON(b []byte) error {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	if v, ok := m["correct"].(bool); ok {
+		a.correct = v
+	} else {
+		return errors.New("data format error (correct)")
+	}
+	if v, ok := m["shortName"].(string); ok {
+		// Since we do not have a link to the content here we store the
+		// question shortname. The caller will have to resolve that back
+		// to the interface.
+		a.questionShortName = v
+	} else {
+		return errors.New("data format error (shortName)")
+	}
+	if v, ok := m["subQuestion"].(string); ok {
+		a.subQuestionTag = v
+		if v == "" {
+			a.subQuestion = nil
+		} else if sq := lookupSubQuestion(v); sq != nil {
+			a.subQuestion = sq
+		}
+	} else {
+		return errors.New("data format error (subQuestion)")
+	}
+	if v, ok := m["time"].(string); ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return errors.New("data format error (time)")
+		}
+		a.time = t
+	}
+	if v, ok := m["responses"].([]interface{}); ok {
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				a.responses = append(a.responses, s)
+			} else {
+				return errors.New("data format error (responses)")
+			}
+		}
+	}
+	if v, ok := m["instance"].([]interface{}); ok {
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				a.instance = append(a.instance, s)
+			} else {
+				return errors.New("data format error (instance)")
+			}
+		}
+	}
+	if v, ok := m["durationMs"].(float64); ok {
+		a.duration = time.Duration(v) * time.Millisecond
+	}
+	// Seeds do not fit in a float64, so they are unmarshalled on their own.
+	var seed struct {
+		Seed *int64 `json:"seed"`
+	}
+	if err := json.Unmarshal(b, &seed); err != nil {
+		return errors.New("data format error (seed)")
+	}
+	a.seed = seed.Seed
+
+	return nil
+}
+
+// fingerprint returns a fingerprint of the content: the questions with
+// their concepts. It changes when questions or concepts are added,
+// removed or renamed.
+func (c *Content) fingerprint() string {
+	lines := make([]string, 0, len(c.Questions))
+	for _, q := range c.Questions {
+		names := make([]string, 0)
+		for _, concept := range q.getConcepts() {
+			names = append(names, concept.shortName)
+		}
+		sort.Strings(names)
+		lines = append(lines, q.getShortName()+":"+strings.Join(names, ","))
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+// parseUserData parses saved student data, in the current or in the
+// old format, and resolves the questions in the content. Answers whose
+// question can not be resolved are returned with a nil question.
+func (c *Content) parseUserData(data []byte) (*userData, error) {
+	ud := &userData{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		// Version 1: just the answers.
+		ud.Version = 1
+		if err := json.Unmarshal(data, &ud.Answers); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, ud); err != nil {
+		return nil, err
+	}
+	if ud.Version > userDataVersion {
+		return nil, errors.New(fmt.Sprintf("student data version %d is newer than this NITS understands (%d)", ud.Version, userDataVersion))
+	}
+
+	// We now need to find the questions in the content by the short name
+	// that unMarshalJSON has put there.
+	for _, a := range ud.Answers {
+		// There is a chance that we are not finding the question if either
+		// the student data has been manipulated (manual testing) or if the
+		// question database has changed and a question has been removed.
+		// Since we have already loaded the record we are going to keep it,
+		// and save it again, but we can not use it.
+		a.question = c.findQuestion(a.questionShortName)
+
+		if a.question != nil {
+			// If the question is a case and the sub question is nil then
+			// the sub question has apparently been removed from the code.
+			if c, ok := a.question.(*Case); ok && a.subQuestion == nil {
+				if trace != nil {
+					trace.println("Not using %s because sq %s not found", c.ShortName, a.subQuestionTag)
+				}
+				a.question = nil
+			}
+		}
+	}
+
+	return ud, nil
+}
+
+// writeFileAtomic writes data to a file by writing it to a temporary file
+// in the same directory first and renaming that to the file. Readers of
+// the file see either the old or the new data, never a mix.
+func writeFileAtomic(fname string, data []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(fname), "."+filepath.Base(fname)+"*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, fname); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// rotateBackups shifts the student data of the profile into the backups:
+// data.1 becomes data.2 and so on, and data becomes data.1. The oldest
+// backup falls off the end.
+func (p *profile) rotateBackups(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(p.dataPath()); os.IsNotExist(err) {
+		return nil
+	}
+	for i := n - 1; i >= 1; i-- {
+		err := os.Rename(p.backupPath(i), p.backupPath(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// The main file is copied rather than renamed, so that there is always
+	// a data file, even if writing the new one fails.
+	data, err := ioutil.ReadFile(p.dataPath())
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.backupPath(1), data, 0644)
+}
+
+// saveUserData saves the student state to the student's profile. Only
+// the registered answers (including the ones that could not be resolved
+// when loading) and the state of the student model are saved.
+func (s *studentState) saveUserData() error {
+	if s.profile == nil {
+		return errors.New("no student profile selected")
+	}
+	model, err := json.Marshal(s.model)
+	if err != nil {
+		return err
+	}
+	ud := &userData{
+		Version: userDataVersion,
+		Content: s.content.fingerprint(),
+		Saved:   time.Now(),
+		Model:   model,
+		Answers: append(append(make([]*answer, 0), s.answers...), s.unresolved...),
+	}
+	data, err := json.MarshalIndent(ud, "", "\t")
+	if err != nil {
+		return err
+	}
+	// Rotating the backups on every save would soon fill them with copies
+	// of the current session, so we only do that once.
+	if !s.backedUp {
+		if err := s.profile.rotateBackups(*backupsFlag); err != nil {
+			return err
+		}
+		s.backedUp = true
+	}
+	return writeFileAtomic(s.profile.dataPath(), data, 0644)
+}
+
+// autosave saves the student data if autosaving is on. Errors are
+// reported but otherwise ignored; the next save will try again.
+func (s *studentState) autosave() {
+	if !*autosaveFlag || s.profile == nil {
+		return
+	}
+	if err := s.saveUserData(); err != nil {
+		println("Autosave failed:", err.Error())
+	}
+}
+
+// readUserData reads and parses the student data of the profile. If the
+// data can not be read or parsed it falls back to the newest backup that
+// can. The returned string names the backup that was used, if any.
+func (s *studentState) readUserData() (*userData, string, error) {
+	data, err := ioutil.ReadFile(s.profile.dataPath())
+	if os.IsNotExist(err) {
+		return nil, "", err
+	}
+	if err == nil {
+		var ud *userData
+		if ud, err = s.content.parseUserData(data); err == nil {
+			return ud, "", nil
+		}
+	}
+	for i := 1; i <= *backupsFlag; i++ {
+		fname := s.profile.backupPath(i)
+		data, err := ioutil.ReadFile(fname)
+		if err != nil {
+			continue
+		}
+		if ud, err := s.content.parseUserData(data); err == nil {
+			return ud, filepath.Base(fname), nil
+		}
+	}
+	return nil, "", err
+}
+
+// loadUserData loads the student state from the student's profile. Only
+// the registered answers are loaded and replayed into the student model.
+// If a question/sub-question can not be found the answer is kept aside,
+// to be saved again. The returned warnings describe anything the student
+// should know about the data.
+func (s *studentState) loadUserData() ([]string, error) {
+	if s.profile == nil {
+		return nil, errors.New("no student profile selected")
+	}
+	ud, backup, err := s.readUserData()
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := make([]string, 0)
+	if backup != "" {
+		warnings = append(warnings, fmt.Sprintf("The student data could not be read; restored it from backup %s.", backup))
+	}
+	if ud.Version < userDataVersion {
+		warnings = append(warnings, fmt.Sprintf("Student data is in format version %d; it will be upgraded to version %d when saved.", ud.Version, userDataVersion))
+	} else if ud.Content != s.content.fingerprint() {
+		warnings = append(warnings, "The content has changed since the student data was saved.")
+	}
+
+	// Copy all the successfully loaded questions to the state and replay
+	// them into the student model.
+	s.answers = make([]*answer, 0, len(ud.Answers))
+	s.unresolved = make([]*answer, 0)
+	s.burnt = make(map[Question]interface{})
+	s.model.reset()
+	for _, a := range ud.Answers {
+		if a.question != nil {
+			s.answers = append(s.answers, a)
+			s.model.update(a)
+			s.burnt[a.question] = nil
+		} else {
+			s.unresolved = append(s.unresolved, a)
+		}
+	}
+	if len(s.unresolved) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d answers refer to questions that no longer exist; they are kept but not used.", len(s.unresolved)))
+	}
+
+	return warnings, nil
+}
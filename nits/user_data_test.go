@@ -0,0 +1,117 @@
+package nits
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseUserData(t *testing.T) {
+	content := &Content{Questions: []Question{
+		&MultipleChoiceQuestion{
+			ShortName: "mc_data",
+			Answers:   []*Answer{{Text: "Yes", Correct: true}, {Text: "No"}},
+		},
+	}}
+
+	// Version 1 is a bare array of answers.
+	ud, err := content.parseUserData([]byte(`[{"shortName":"mc_data","correct":true,"subQuestion":""},{"shortName":"mc_gone","correct":false,"subQuestion":""}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ud.Version != 1 || len(ud.Answers) != 2 {
+		t.Fatalf("parseUserData(v1); got: version %d with %d answers, want: version 1 with 2 answers", ud.Version, len(ud.Answers))
+	}
+	if ud.Answers[0].question == nil || ud.Answers[1].question != nil {
+		t.Errorf("parseUserData(v1); questions not resolved correctly")
+	}
+	if ud.Answers[0].seed != nil {
+		t.Errorf("parseUserData(v1); got: seed %d, want: none", *ud.Answers[0].seed)
+	}
+
+	// Version 2 round trip.
+	seed := int64(1602958123456789012)
+	a := &answer{
+		questionShortName: "mc_data",
+		correct:           false,
+		time:              time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+		responses:         []string{"No", "Yes"},
+		duration:          1500 * time.Millisecond,
+		instance:          []string{"bruce", "damage1"},
+		seed:              &seed,
+	}
+	data, err := json.Marshal(&userData{Version: userDataVersion, Content: content.fingerprint(), Answers: []*answer{a}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ud, err = content.parseUserData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ud.Answers[0]
+	if ud.Version != userDataVersion || ud.Content != content.fingerprint() {
+		t.Errorf("parseUserData(v2); got: version %d content %s", ud.Version, ud.Content)
+	}
+	if !got.time.Equal(a.time) || got.duration != a.duration || len(got.responses) != 2 || got.responses[1] != "Yes" || len(got.instance) != 2 || got.instance[1] != "damage1" {
+		t.Errorf("parseUserData(v2); got: %v, want: %v", got, a)
+	}
+	if got.seed == nil || *got.seed != seed {
+		t.Errorf("parseUserData(v2); got: seed %v, want: %d", got.seed, seed)
+	}
+
+	// Newer versions are refused.
+	if _, err := content.parseUserData([]byte(`{"version": 99, "answers": []}`)); err == nil {
+		t.Errorf("parseUserData(v99); want: error")
+	}
+}
+
+func TestSaveAndRecover(t *testing.T) {
+	home, err := ioutil.TempDir("", "nits_home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	content := &Content{Questions: []Question{
+		&MultipleChoiceQuestion{
+			ShortName: "mc_save",
+			Answers:   []*Answer{{Text: "Yes", Correct: true}, {Text: "No"}},
+		},
+	}}
+	p, err := newProfile("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	useBackend(t, &nativeBKT{})
+	model, _ := newStudentModel("bkt")
+	state := newStudentState(content, model)
+	state.profile = p
+
+	// Two sessions: the second one rotates the data of the first into a
+	// backup. Each answer keeps the seed of its session.
+	state.setSeed(1)
+	state.registerAnswer(content.Questions[0], nil, []string{"Yes"})
+	state.backedUp = false
+	state.setSeed(2)
+	state.registerAnswer(content.Questions[0], nil, []string{"No", "Yes"})
+	if _, err := os.Stat(p.backupPath(1)); err != nil {
+		t.Fatalf("no backup after the second session: %v", err)
+	}
+
+	if err := ioutil.WriteFile(p.dataPath(), []byte(`{"version": 2, "answ`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	warnings, err := state.loadUserData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.answers) != 1 || len(warnings) != 1 {
+		t.Errorf("loadUserData(); got: %d answers and warnings %v, want: 1 answer restored from backup", len(state.answers), warnings)
+	} else if seed := state.answers[0].seed; seed == nil || *seed != 1 {
+		t.Errorf("loadUserData(); got: seed %v, want: the seed 1 of the first session", seed)
+	}
+}
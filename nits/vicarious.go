@@ -0,0 +1,99 @@
+package nits
+
+// This file implements the vicarious defendants sub question. It is a
+// variant of the defendants sub question for damages where some persons
+// are vicariously liable for the persons who breached a duty.
+
+import (
+	"math/rand"
+	"strings"
+)
+
+type vicariousDefendantsSubQuestion struct{}
+
+func (v *vicariousDefendantsSubQuestion) getTag() string {
+	return "vicariousDefendants"
+}
+
+func (v *vicariousDefendantsSubQuestion) getConcepts() []*Concept {
+	return []*Concept{Defendant0, VicariousLiability1}
+}
+
+func (v *vicariousDefendantsSubQuestion) applies(pp *preprocessedCase) bool {
+	return len(pp.vicariousDamages()) > 0
+}
+
+var _ = addSubQuestion(&vicariousDefendantsSubQuestion{})
+
+// vicariousDamages returns the damages in a case for which someone is
+// vicariously liable.
+func (p *preprocessedCase) vicariousDamages() []InjuryOrDamage {
+	result := make([]InjuryOrDamage, 0)
+	for dam := range p.injuriesOrDamages {
+		if len(p.vicariouslyLiable(collectPersonsFromDuties(findDuties(dam)))) > 0 {
+			result = append(result, dam)
+		}
+	}
+	p.sortDamages(result)
+	return result
+}
+
+// newInstance picks a random damage for which someone is vicariously
+// liable.
+func (v *vicariousDefendantsSubQuestion) newInstance(pp *preprocessedCase, r *rand.Rand) *sqInstance {
+	dams := pp.vicariousDamages()
+	if len(dams) == 0 {
+		return nil
+	}
+	return pp.newInstance(v, dams[r.Intn(len(dams))])
+}
+
+// ask asks the vicarious defendants sub question.
+func (v *vicariousDefendantsSubQuestion) ask(c *Case, ui *userInterface, state *studentState, inst *sqInstance) bool {
+	pp := c.preprocess()
+	dam, ok := pp.ref(inst, 0).(InjuryOrDamage)
+	if !ok {
+		return badInstance(ui, inst)
+	}
+	direct := collectPersonsFromDuties(findDuties(dam))
+	vicarious := pp.vicariouslyLiable(direct)
+
+	displayQuestion := func([]string) bool {
+		ui.newline()
+		ui.println("Consider the following damage:")
+		ui.println(dam.GetDescription())
+		ui.println("Please enter the names of all people who could be held responsible for this,")
+		ui.println("including the ones who are liable for the acts of others:")
+		return false
+	}
+
+	displayQuestion(nil)
+	pushSubQuestionCommandContext(ui, displayQuestion)
+	defer ui.popCommandContext()
+
+	responses := make([]string, 0)
+
+	for {
+		names, ret := ui.getNames()
+		if ret {
+			return ret
+		}
+		responses = append(responses, strings.Join(names, ", "))
+		if namesMatch(names, union(direct, vicarious)) {
+			ui.println("Correct!")
+			break
+		}
+		if namesMatch(names, direct) {
+			ui.println("Those are the people who breached a duty, but somebody else is liable for their acts :-(")
+			continue
+		}
+		ui.println("Incorrect :-(")
+	}
+	for _, r := range pp.relationships {
+		if _, ok := vicarious[r.Superior]; ok {
+			ui.println("- %s %s %s: %s", r.Superior.Name, r.Kind, r.Subordinate.Name, r.Description)
+		}
+	}
+	state.registerAnswer(c, v, responses)
+	return false
+}